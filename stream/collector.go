@@ -0,0 +1,285 @@
+package stream
+
+import (
+	"strings"
+
+	"golang.org/x/exp/constraints"
+)
+
+// Number is the constraint of the types that can be summed and averaged.
+type Number interface {
+	constraints.Integer | constraints.Float
+}
+
+// Collector describes a mutable reduction of elements of type T into a result of
+// type R, through an intermediate accumulation of type A:
+//   - Supplier creates an empty accumulation. For parallel streams each goroutine
+//     gets its own, so it can be a mutable container such as a map or a slice.
+//   - Accumulator adds an element to an accumulation and returns it.
+//   - Combiner merges two partial accumulations of a parallel stream and returns
+//     the result. It may modify and return any of them.
+//   - Finisher turns the final accumulation into the result.
+type Collector[T, A, R any] struct {
+	Supplier    func() A
+	Accumulator func(A, T) A
+	Combiner    func(A, A) A
+	Finisher    func(A) R
+}
+
+// Collect performs a mutable reduction of the elements of the stream with the
+// given Collector and returns its result. For parallel streams every goroutine
+// accumulates its elements in its own accumulation, as Reduce does, and the partial
+// accumulations are merged with the Combiner of the Collector.
+func Collect[T, A, R any](s Stream[T], collector Collector[T, A, R]) R {
+	defer s.pipe.close()
+	return collect(s, collector)
+}
+
+// collect is Collect without closing the pipeline.
+func collect[T, A, R any](s Stream[T], collector Collector[T, A, R]) R {
+	return collector.Finisher(reduce(s, collector.Supplier, collector.Accumulator, collector.Combiner))
+}
+
+// ToList returns a Collector that accumulates the elements into a slice.
+func ToList[T any]() Collector[T, []T, []T] {
+	return Collector[T, []T, []T]{
+		Supplier:    func() []T { return []T{} },
+		Accumulator: func(acc []T, v T) []T { return append(acc, v) },
+		Combiner:    func(a, b []T) []T { return append(a, b...) },
+		Finisher:    func(acc []T) []T { return acc },
+	}
+}
+
+// Counting returns a Collector that counts the elements.
+func Counting[T any]() Collector[T, int, int] {
+	return Collector[T, int, int]{
+		Supplier:    func() int { return 0 },
+		Accumulator: func(acc int, _ T) int { return acc + 1 },
+		Combiner:    func(a, b int) int { return a + b },
+		Finisher:    func(acc int) int { return acc },
+	}
+}
+
+// Summing returns a Collector that sums the values extracted by fn from the elements.
+func Summing[T any, N Number](fn func(T) N) Collector[T, N, N] {
+	return Collector[T, N, N]{
+		Supplier:    func() N { return 0 },
+		Accumulator: func(acc N, v T) N { return acc + fn(v) },
+		Combiner:    func(a, b N) N { return a + b },
+		Finisher:    func(acc N) N { return acc },
+	}
+}
+
+// GroupingBy returns a Collector that groups the elements by the key returned by
+// keyFn, and reduces the elements of every group with the downstream Collector,
+// e.g. GroupingBy(keyFn, Counting[T]()) counts the elements per key. For parallel
+// streams each goroutine groups its elements in its own map, and the maps are
+// merged with the Combiner of the downstream Collector.
+func GroupingBy[T any, K comparable, A, R any](keyFn func(T) K, downstream Collector[T, A, R]) Collector[T, map[K]A, map[K]R] {
+	return Collector[T, map[K]A, map[K]R]{
+		Supplier: func() map[K]A { return make(map[K]A) },
+		Accumulator: func(groups map[K]A, v T) map[K]A {
+			k := keyFn(v)
+			acc, ok := groups[k]
+			if !ok {
+				acc = downstream.Supplier()
+			}
+			groups[k] = downstream.Accumulator(acc, v)
+			return groups
+		},
+		Combiner: func(a, b map[K]A) map[K]A {
+			for k, accB := range b {
+				if accA, ok := a[k]; ok {
+					a[k] = downstream.Combiner(accA, accB)
+				} else {
+					a[k] = accB
+				}
+			}
+			return a
+		},
+		Finisher: func(groups map[K]A) map[K]R {
+			res := make(map[K]R, len(groups))
+			for k, acc := range groups {
+				res[k] = downstream.Finisher(acc)
+			}
+			return res
+		},
+	}
+}
+
+// ToSet returns a Collector that accumulates the distinct elements into a set.
+func ToSet[T comparable]() Collector[T, map[T]struct{}, map[T]struct{}] {
+	return Collector[T, map[T]struct{}, map[T]struct{}]{
+		Supplier: func() map[T]struct{} { return make(map[T]struct{}) },
+		Accumulator: func(set map[T]struct{}, v T) map[T]struct{} {
+			set[v] = struct{}{}
+			return set
+		},
+		Combiner: func(a, b map[T]struct{}) map[T]struct{} {
+			for v := range b {
+				a[v] = struct{}{}
+			}
+			return a
+		},
+		Finisher: func(set map[T]struct{}) map[T]struct{} { return set },
+	}
+}
+
+// ToMap returns a Collector that accumulates the elements into a map, whose keys
+// and values are extracted from each element by keyFn and valueFn. When several
+// elements have the same key, their values are merged with merge; if merge is nil
+// one of the values is kept, which is the last one for sequential streams.
+func ToMap[T any, K comparable, V any](keyFn func(T) K, valueFn func(T) V, merge func(V, V) V) Collector[T, map[K]V, map[K]V] {
+	put := func(m map[K]V, k K, v V) {
+		if old, ok := m[k]; ok && merge != nil {
+			v = merge(old, v)
+		}
+		m[k] = v
+	}
+	return Collector[T, map[K]V, map[K]V]{
+		Supplier: func() map[K]V { return make(map[K]V) },
+		Accumulator: func(m map[K]V, v T) map[K]V {
+			put(m, keyFn(v), valueFn(v))
+			return m
+		},
+		Combiner: func(a, b map[K]V) map[K]V {
+			for k, v := range b {
+				put(a, k, v)
+			}
+			return a
+		},
+		Finisher: func(m map[K]V) map[K]V { return m },
+	}
+}
+
+// Joining returns a Collector that concatenates the strings of the stream,
+// separated by sep. For parallel streams the strings are joined in no particular
+// order, unless the stream is ordered.
+func Joining(sep string) Collector[string, []string, string] {
+	return CollectingAndThen(ToList[string](), func(parts []string) string {
+		return strings.Join(parts, sep)
+	})
+}
+
+// Averaging returns a Collector that computes the arithmetic mean of the values
+// extracted by fn from the elements, or 0 if there are no elements.
+func Averaging[T any, N Number](fn func(T) N) Collector[T, Statistics[N], float64] {
+	return CollectingAndThen(Summarizing(fn), func(stats Statistics[N]) float64 {
+		return stats.Average()
+	})
+}
+
+// Statistics holds the count, sum, minimum and maximum of a set of numbers.
+type Statistics[N Number] struct {
+	Count int
+	Sum   N
+	// Min and Max are zero when Count is zero.
+	Min N
+	Max N
+}
+
+// Average returns the arithmetic mean of the numbers, or 0 if Count is zero.
+func (s Statistics[N]) Average() float64 {
+	if s.Count == 0 {
+		return 0
+	}
+	return float64(s.Sum) / float64(s.Count)
+}
+
+func (s Statistics[N]) add(n N) Statistics[N] {
+	if s.Count == 0 || n < s.Min {
+		s.Min = n
+	}
+	if s.Count == 0 || n > s.Max {
+		s.Max = n
+	}
+	s.Count++
+	s.Sum += n
+	return s
+}
+
+func (s Statistics[N]) merge(o Statistics[N]) Statistics[N] {
+	if o.Count == 0 {
+		return s
+	}
+	if s.Count == 0 {
+		return o
+	}
+	return Statistics[N]{
+		Count: s.Count + o.Count,
+		Sum:   s.Sum + o.Sum,
+		Min:   min(s.Min, o.Min),
+		Max:   max(s.Max, o.Max),
+	}
+}
+
+// Summarizing returns a Collector that computes the Statistics of the values
+// extracted by fn from the elements.
+func Summarizing[T any, N Number](fn func(T) N) Collector[T, Statistics[N], Statistics[N]] {
+	return Collector[T, Statistics[N], Statistics[N]]{
+		Supplier:    func() Statistics[N] { return Statistics[N]{} },
+		Accumulator: func(acc Statistics[N], v T) Statistics[N] { return acc.add(fn(v)) },
+		Combiner:    Statistics[N].merge,
+		Finisher:    func(acc Statistics[N]) Statistics[N] { return acc },
+	}
+}
+
+// PartitioningBy returns a Collector that splits the elements in the ones that
+// match the predicate (key true) and the ones that don't (key false), and reduces
+// each partition with the downstream Collector. The result always has both keys.
+func PartitioningBy[T, A, R any](predicate func(T) bool, downstream Collector[T, A, R]) Collector[T, map[bool]A, map[bool]R] {
+	grouping := GroupingBy(predicate, downstream)
+	grouping.Supplier = func() map[bool]A {
+		return map[bool]A{true: downstream.Supplier(), false: downstream.Supplier()}
+	}
+	return grouping
+}
+
+// Mapping returns a Collector that applies fn to the elements before passing them
+// to the downstream Collector, e.g. GroupingBy(keyFn, Mapping(fn, ToList[O]())).
+func Mapping[T, O, A, R any](fn func(T) O, downstream Collector[O, A, R]) Collector[T, A, R] {
+	return Collector[T, A, R]{
+		Supplier:    downstream.Supplier,
+		Accumulator: func(acc A, v T) A { return downstream.Accumulator(acc, fn(v)) },
+		Combiner:    downstream.Combiner,
+		Finisher:    downstream.Finisher,
+	}
+}
+
+// Filtering returns a Collector that only passes the elements matching the
+// predicate to the downstream Collector.
+func Filtering[T, A, R any](predicate func(T) bool, downstream Collector[T, A, R]) Collector[T, A, R] {
+	return Collector[T, A, R]{
+		Supplier: downstream.Supplier,
+		Accumulator: func(acc A, v T) A {
+			if predicate(v) {
+				return downstream.Accumulator(acc, v)
+			}
+			return acc
+		},
+		Combiner: downstream.Combiner,
+		Finisher: downstream.Finisher,
+	}
+}
+
+// CollectingAndThen returns a Collector that applies finisher to the result of the
+// downstream Collector.
+func CollectingAndThen[T, A, R, RR any](downstream Collector[T, A, R], finisher func(R) RR) Collector[T, A, RR] {
+	return Collector[T, A, RR]{
+		Supplier:    downstream.Supplier,
+		Accumulator: downstream.Accumulator,
+		Combiner:    downstream.Combiner,
+		Finisher:    func(acc A) RR { return finisher(downstream.Finisher(acc)) },
+	}
+}
+
+// Reducing returns a Collector that reduces the elements with the given identity
+// value and associative function, like Reduce does.
+func Reducing[T any](identity T, fn func(T, T) T) Collector[T, T, T] {
+	return Collector[T, T, T]{
+		Supplier:    func() T { return identity },
+		Accumulator: fn,
+		Combiner:    fn,
+		Finisher:    func(acc T) T { return acc },
+	}
+}
@@ -0,0 +1,116 @@
+package stream
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestCollect(t *testing.T) {
+	words := func() Stream[string] {
+		return Of("apple", "avocado", "banana", "blueberry", "cherry", "apricot")
+	}
+	firstLetter := func(s string) byte { return s[0] }
+	length := func(s string) int { return len(s) }
+
+	t.Run("counting per key", func(t *testing.T) {
+		for _, p := range []int{1, 3} {
+			got := Collect(words().Parallel(p), GroupingBy(firstLetter, Counting[string]()))
+			want := map[byte]int{'a': 3, 'b': 2, 'c': 1}
+			if !reflect.DeepEqual(got, want) {
+				t.Errorf("parallel %d: Collect() = %v, want %v", p, got, want)
+			}
+		}
+	})
+	t.Run("summing per key", func(t *testing.T) {
+		got := Collect(words(), GroupingBy(firstLetter, Summing(length)))
+		want := map[byte]int{'a': 19, 'b': 15, 'c': 6}
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("Collect() = %v, want %v", got, want)
+		}
+	})
+	t.Run("nested grouping", func(t *testing.T) {
+		got := Collect(words(), GroupingBy(firstLetter, GroupingBy(length, Counting[string]())))
+		want := map[byte]map[int]int{'a': {5: 1, 7: 2}, 'b': {6: 1, 9: 1}, 'c': {6: 1}}
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("Collect() = %v, want %v", got, want)
+		}
+	})
+	t.Run("to list", func(t *testing.T) {
+		got := Collect(Range(0, 5), ToList[int]())
+		if want := []int{0, 1, 2, 3, 4}; !reflect.DeepEqual(got, want) {
+			t.Errorf("Collect() = %v, want %v", got, want)
+		}
+	})
+}
+
+func TestCollectors(t *testing.T) {
+	type person struct {
+		name string
+		age  int
+	}
+	people := func() Stream[person] {
+		return Of(person{"Ann", 35}, person{"Tom", 12}, person{"Bob", 42})
+	}
+	name := func(p person) string { return p.name }
+	age := func(p person) int { return p.age }
+	adult := func(p person) bool { return p.age >= 18 }
+
+	for _, p := range []int{1, 3} {
+		if got, want := Collect(Of(1, 2, 2, 3, 1).Parallel(p), ToSet[int]()),
+			map[int]struct{}{1: {}, 2: {}, 3: {}}; !reflect.DeepEqual(got, want) {
+			t.Errorf("parallel %d: ToSet = %v, want %v", p, got, want)
+		}
+		if got, want := Collect(people().Parallel(p), ToMap(name, age, nil)),
+			map[string]int{"Ann": 35, "Tom": 12, "Bob": 42}; !reflect.DeepEqual(got, want) {
+			t.Errorf("parallel %d: ToMap = %v, want %v", p, got, want)
+		}
+		if got, want := Collect(people().Parallel(p), ToMap(adult, age, func(a, b int) int { return a + b })),
+			map[bool]int{true: 77, false: 12}; !reflect.DeepEqual(got, want) {
+			t.Errorf("parallel %d: ToMap with merge = %v, want %v", p, got, want)
+		}
+		if got, want := Collect(people().Parallel(p), Summarizing(age)),
+			(Statistics[int]{Count: 3, Sum: 89, Min: 12, Max: 42}); got != want {
+			t.Errorf("parallel %d: Summarizing = %+v, want %+v", p, got, want)
+		}
+		if got, want := Collect(Range(1, 5).Parallel(p), Reducing(1, func(a, b int) int { return a * b })), 24; got != want {
+			t.Errorf("parallel %d: Reducing = %v, want %v", p, got, want)
+		}
+	}
+
+	tests := []struct {
+		name string
+		got  any
+		want any
+	}{
+		{"Joining", Collect(Map(people(), name), Joining(", ")), "Ann, Tom, Bob"},
+		{"Joining empty", Collect(Of[string](), Joining(", ")), ""},
+		{"Averaging", Collect(people(), Averaging(age)), float64(89) / 3},
+		{"Averaging empty", Collect(Of[person](), Averaging(age)), float64(0)},
+		{"Summarizing empty", Collect(Of[person](), Summarizing(age)), Statistics[int]{}},
+		{
+			"PartitioningBy",
+			Collect(people(), PartitioningBy(adult, Mapping(name, Joining(", ")))),
+			map[bool]string{true: "Ann, Bob", false: "Tom"},
+		},
+		{
+			"PartitioningBy keeps both keys",
+			Collect(Of(1, 2, 3), PartitioningBy(func(n int) bool { return n > 5 }, Counting[int]())),
+			map[bool]int{true: 0, false: 3},
+		},
+		{
+			"Filtering",
+			Collect(people(), GroupingBy(adult, Filtering(func(p person) bool { return p.age < 40 }, Counting[person]()))),
+			map[bool]int{true: 1, false: 1},
+		},
+		{
+			"CollectingAndThen",
+			Collect(Of(3, 1, 2), CollectingAndThen(ToList[int](), func(l []int) int { return len(l) })),
+			3,
+		},
+	}
+	for _, tt := range tests {
+		if !reflect.DeepEqual(tt.got, tt.want) {
+			t.Errorf("%s = %v, want %v", tt.name, tt.got, tt.want)
+		}
+	}
+}
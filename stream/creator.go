@@ -7,20 +7,77 @@ import (
 	"golang.org/x/exp/constraints"
 )
 
-type stream[T any] struct {
+// Stream is a lazily evaluated sequence of elements supporting sequential and
+// parallel aggregate operations. Elements are pulled on demand from the pull
+// function of the stream, so nothing happens until a terminal operation (ForEach,
+// ToSlice, Reduce...) is invoked.
+//
+// A Stream value must be consumed at most once. Operators defined outside this
+// package can be built on top of the pull function with Transform. The zero Stream
+// is not a valid stream, except as the result of a FlatMap mapper; use Empty for a
+// stream without elements.
+type Stream[T any] struct {
+	config
+	nextFn func() (T, bool)
+	// seqFn pulls the elements of an ordered stream tagged with their position in
+	// the encounter order. It is nil for the ordered streams whose nextFn already
+	// returns the elements in encounter order.
+	seqFn func() (seqItem[T], bool)
+	// split divides the stream in disjoint parts for the goroutines of a parallel
+	// terminal operation. It is nil for the streams that can't be split, whose
+	// nextFn is then shared by the goroutines.
+	split splitFn[T]
+	// limit replaces Limit and Take for the streams that can keep only their first
+	// n elements more cheaply than by producing all of them, i.e. Sorted, which then
+	// keeps the n lowest elements in a bounded heap (see BottomKStream). It is nil
+	// for the other streams, and not inherited by the streams derived from this one.
+	limit func(n int) Stream[T]
+}
+
+// config holds the attributes of a stream that are inherited by the streams
+// derived from it.
+type config struct {
 	// sorted   bool
-	parallel int //will be used in terminal operation or stateful operation
-	nextFn   func() (T, bool)
+	parallel int       //will be used in terminal operation or stateful operation
+	ordered  bool      // whether the encounter order must be preserved, see Ordered
+	pipe     *pipeline // run-time state shared with the other stages of the pipeline
+	clock    Clock     // used by the time-based operators, SystemClock if nil
+}
+
+func newConfig() config {
+	return config{parallel: 1, pipe: newPipeline()}
+}
+
+// concurrent returns whether terminal operations consume the stream from several
+// goroutines. Ordered streams are always consumed from a single goroutine, in
+// encounter order, while their stages run in parallel (see Ordered).
+func (c config) concurrent() bool {
+	return c.parallel > 1 && !c.ordered
 }
 
-func Of[T any](elems ...T) stream[T] {
+func Of[T any](elems ...T) Stream[T] {
 	return OfSlice(elems)
 }
 
-func OfSlice[T any](elems []T) stream[T] {
+// Empty returns a stream without elements.
+func Empty[T any]() Stream[T] {
+	return OfSlice[T](nil)
+}
+
+// orEmpty returns s, or an empty stream if s is the zero Stream.
+func (s Stream[T]) orEmpty() Stream[T] {
+	if s.nextFn == nil {
+		return Empty[T]()
+	}
+	return s
+}
+
+// OfSlice returns a stream of the elements of the given slice. Parallel terminal
+// operations split the slice between their goroutines instead of sharing it.
+func OfSlice[T any](elems []T) Stream[T] {
 	i := int64(0)
-	return stream[T]{
-		parallel: 1,
+	return Stream[T]{
+		config: newConfig(),
 		nextFn: func() (T, bool) {
 			i := atomic.AddInt64(&i, 1)
 			var v T
@@ -30,12 +87,13 @@ func OfSlice[T any](elems []T) stream[T] {
 			v = elems[i-1]
 			return v, true
 		},
+		split: splitIndexed(len(elems), func(i int) T { return elems[i] }),
 	}
 }
 
-func OfChannel[T any](ch chan T) stream[T] {
-	return stream[T]{
-		parallel: 1,
+func OfChannel[T any](ch chan T) Stream[T] {
+	return Stream[T]{
+		config: newConfig(),
 		nextFn: func() (T, bool) {
 			v, ok := <-ch
 			return v, ok
@@ -43,19 +101,25 @@ func OfChannel[T any](ch chan T) stream[T] {
 	}
 }
 
-func Generate[T any](fn func() (T, bool)) stream[T] {
-	return stream[T]{
-		parallel: 1,
-		nextFn:   fn,
+func Generate[T any](fn func() (T, bool)) Stream[T] {
+	return Stream[T]{
+		config: newConfig(),
+		nextFn: fn,
 	}
 }
 
 // Range returns a stream of integers from start (inclusive) to end (exclusive)
-// The generating process is thread-safe
-func Range[T constraints.Integer](start, end T) stream[T] {
+// The generating process is thread-safe, and parallel terminal operations split
+// the range between their goroutines instead of sharing it.
+func Range[T constraints.Integer](start, end T) Stream[T] {
 	lock := &sync.Mutex{}
-	return stream[T]{
-		parallel: 1,
+	var split splitFn[T]
+	if start < end {
+		first := start
+		split = splitIndexed(int(end-start), func(i int) T { return first + T(i) })
+	}
+	return Stream[T]{
+		config: newConfig(),
 		nextFn: func() (T, bool) {
 			lock.Lock()
 			defer lock.Unlock()
@@ -66,5 +130,38 @@ func Range[T constraints.Integer](start, end T) stream[T] {
 			start++
 			return result, true
 		},
+		split: split,
+	}
+}
+
+// Defer returns a stream whose elements are the elements of the stream returned by
+// factory. The factory is invoked only once, when the first element is pulled, so
+// the resources of the stream (a file, a query, a big slice...) are only acquired
+// when a terminal operation actually runs, even if several goroutines of a parallel
+// stream pull at the same time. The stream returned by factory joins the pipeline
+// of the deferred stream: it is closed along with it, its errors are returned by the
+// error-aware terminals (ToSliceErr, ForEachErr) and the ErrorPolicy applies to it.
+// It is split between the goroutines if it can be.
+func Defer[T any](factory func() Stream[T]) Stream[T] {
+	c := newConfig()
+	var inner Stream[T]
+	once := sync.Once{}
+	open := func() {
+		inner = factory()
+		c.pipe.addParent(inner.pipe)
+	}
+	return Stream[T]{
+		config: c,
+		nextFn: func() (T, bool) {
+			once.Do(open)
+			return inner.nextFn()
+		},
+		split: func(n int) []func() (T, bool) {
+			once.Do(open)
+			if inner.split == nil {
+				return nil
+			}
+			return inner.split(n)
+		},
 	}
 }
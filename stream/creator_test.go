@@ -0,0 +1,62 @@
+package stream
+
+import (
+	"reflect"
+	"strconv"
+	"sync/atomic"
+	"testing"
+)
+
+func TestDefer(t *testing.T) {
+	calls := int32(0)
+	closed := false
+	factory := func() Stream[int] {
+		atomic.AddInt32(&calls, 1)
+		s := Range(0, 1000)
+		s.pipe.onClose(func() error { closed = true; return nil })
+		return s
+	}
+
+	s := Defer(factory).Map(func(n int) int { return n * 2 })
+	if calls != 0 {
+		t.Fatalf("factory invoked %d times before the terminal operation", calls)
+	}
+	for _, p := range []int{1, 8} {
+		atomic.StoreInt32(&calls, 0)
+		closed = false
+		got := Defer(factory).Parallel(p).Map(func(n int) int { return n * 2 }).Reduce(0, func(a, b int) int { return a + b })
+		if got != 999000 {
+			t.Errorf("parallel %d: Reduce() = %d, want 999000", p, got)
+		}
+		if calls != 1 {
+			t.Errorf("parallel %d: factory invoked %d times, want 1", p, calls)
+		}
+		if !closed {
+			t.Errorf("parallel %d: deferred stream not closed", p)
+		}
+	}
+	if got := s.Limit(3).ToSlice(); !reflect.DeepEqual(got, []int{0, 2, 4}) {
+		t.Errorf("ToSlice() = %v", got)
+	}
+}
+
+func TestDefer_Errors(t *testing.T) {
+	deferred := func() Stream[int] {
+		return Defer(func() Stream[int] { return MapErr(Of("1", "x", "3"), strconv.Atoi) })
+	}
+	got, err := deferred().ToSliceErr()
+	if !reflect.DeepEqual(got, []int{1}) || err == nil {
+		t.Errorf("ToSliceErr() = %v, %v, want [1] and the Atoi error", got, err)
+	}
+
+	got, err = deferred().WithErrorPolicy(SkipAndCollect).ToSliceErr()
+	if !reflect.DeepEqual(got, []int{1, 3}) || err == nil {
+		t.Errorf("SkipAndCollect: ToSliceErr() = %v, %v, want [1 3] and the Atoi error", got, err)
+	}
+
+	var routed []error
+	got, err = deferred().WithErrorPolicy(RouteErrors(func(err error) { routed = append(routed, err) })).ToSliceErr()
+	if !reflect.DeepEqual(got, []int{1, 3}) || err != nil || len(routed) != 1 {
+		t.Errorf("RouteErrors: ToSliceErr() = %v, %v, routed %v", got, err, routed)
+	}
+}
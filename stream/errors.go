@@ -0,0 +1,205 @@
+package stream
+
+import "fmt"
+
+// PanicError is the value re-panicked on the goroutine calling a terminal operation
+// when a user function panics in one of the goroutines of a parallel stream. The other
+// goroutines stop pulling elements before the panic is re-raised, so a recover around
+// the terminal call works the same as for a sequential stream.
+type PanicError struct {
+	// Value is the value passed to panic.
+	Value any
+	// Stack is the stack trace of the goroutine that panicked.
+	Stack []byte
+}
+
+func (e *PanicError) Error() string {
+	return fmt.Sprintf("stream: panic in parallel worker: %v\n\n%s", e.Value, e.Stack)
+}
+
+// Unwrap returns the panic value if it is an error, or nil otherwise.
+func (e *PanicError) Unwrap() error {
+	err, _ := e.Value.(error)
+	return err
+}
+
+// ErrorPolicy defines what the error-aware operators (MapErr, FilterErr, FlatMapErr,
+// ForEachErr) do when they fail on an element. The policy applies to the whole
+// pipeline and is set with WithErrorPolicy. FailFast is the default.
+type ErrorPolicy struct {
+	skip    bool
+	handler func(error)
+}
+
+var (
+	// FailFast stops the stream at the first error: all the parallel workers stop
+	// pulling, and the error-aware terminals (ToSliceErr, ForEachErr) return that error.
+	FailFast = ErrorPolicy{}
+	// SkipAndCollect drops the elements that failed and keeps processing the stream.
+	// The error-aware terminals return all the errors, joined with errors.Join.
+	SkipAndCollect = ErrorPolicy{skip: true}
+)
+
+// RouteErrors returns an ErrorPolicy that drops the elements that failed and passes
+// their errors to handler, so the error-aware terminals return nil. For parallel
+// streams the handler is invoked concurrently from several goroutines.
+func RouteErrors(handler func(error)) ErrorPolicy {
+	return ErrorPolicy{skip: true, handler: handler}
+}
+
+// WithErrorPolicy sets the ErrorPolicy of the pipeline the stream belongs to, which
+// includes the stages before and after it.
+func (s Stream[T]) WithErrorPolicy(policy ErrorPolicy) Stream[T] {
+	s.pipe.setPolicy(policy)
+	return s
+}
+
+// MapErr returns a stream consisting of the results of applying fn to the elements
+// of the input stream. When fn returns an error, the element is handled according
+// to the ErrorPolicy of the pipeline. For parallel streams, ordered or not, fn is
+// invoked concurrently.
+func MapErr[I any, O any](s Stream[I], fn func(I) (O, error)) Stream[O] {
+	if s.ordered {
+		return mapSequencedWhile(s, func(vals []I) ([]O, error) {
+			out := make([]O, 0, len(vals))
+			for _, v := range vals {
+				o, err := fn(v)
+				if err == nil {
+					out = append(out, o)
+				} else if !s.pipe.skips() {
+					// reported once the elements before it are delivered
+					return out, err
+				} else {
+					s.pipe.report(err)
+				}
+			}
+			return out, nil
+		})
+	}
+	return stateless(s, func(next func() (I, bool)) func() (O, bool) {
+		return func() (O, bool) {
+			var zeroVal O
+			for {
+				if s.pipe.isFailed() {
+					return zeroVal, false
+				}
+				v, hasNext := next()
+				if !hasNext {
+					return zeroVal, false
+				}
+				o, err := fn(v)
+				if err == nil {
+					return o, true
+				}
+				if !s.pipe.report(err) {
+					return zeroVal, false
+				}
+			}
+		}
+	})
+}
+
+// FilterErr returns a stream consisting of the elements of the input stream that
+// match the given predicate. When the predicate returns an error, the element is
+// handled according to the ErrorPolicy of the pipeline. For parallel streams,
+// ordered or not, the predicate is invoked concurrently.
+// This function is equivalent to invoking input.FilterErr(predicate) as method.
+func FilterErr[T any](input Stream[T], predicate func(T) (bool, error)) Stream[T] {
+	return input.FilterErr(predicate)
+}
+
+func (s Stream[T]) FilterErr(predicate func(T) (bool, error)) Stream[T] {
+	if s.ordered {
+		return mapSequencedWhile(s, func(vals []T) ([]T, error) {
+			kept := vals[:0]
+			for _, v := range vals {
+				ok, err := predicate(v)
+				if err != nil {
+					if !s.pipe.skips() {
+						return kept, err
+					}
+					s.pipe.report(err)
+				} else if ok {
+					kept = append(kept, v)
+				}
+			}
+			return kept, nil
+		})
+	}
+	return stateless(s, func(next func() (T, bool)) func() (T, bool) {
+		return func() (T, bool) {
+			var zeroVal T
+			for {
+				if s.pipe.isFailed() {
+					return zeroVal, false
+				}
+				v, hasNext := next()
+				if !hasNext {
+					return zeroVal, false
+				}
+				ok, err := predicate(v)
+				if err != nil {
+					if !s.pipe.report(err) {
+						return zeroVal, false
+					}
+					continue
+				}
+				if ok {
+					return v, true
+				}
+			}
+		}
+	})
+}
+
+// FlatMapErr is like FlatMap, but the mapper may fail. When the mapper returns an
+// error, the element is handled according to the ErrorPolicy of the pipeline.
+func FlatMapErr[IN, OUT any](input Stream[IN], mapper func(IN) (Stream[OUT], error)) Stream[OUT] {
+	return FlatMap(MapErr(input, mapper), func(s Stream[OUT]) Stream[OUT] {
+		return s
+	})
+}
+
+// failFast returns a stream that stops as soon as an error stops its pipeline.
+func (s Stream[T]) failFast() Stream[T] {
+	return stateless(s, func(next func() (T, bool)) func() (T, bool) {
+		return func() (T, bool) {
+			var zeroVal T
+			if s.pipe.isFailed() {
+				return zeroVal, false
+			}
+			v, hasNext := next()
+			if !hasNext || s.pipe.isFailed() {
+				return zeroVal, false
+			}
+			return v, true
+		}
+	})
+}
+
+// ToSliceErr is like ToSlice, but it also returns the errors reported by the
+// error-aware operators of the pipeline, according to its ErrorPolicy. With the
+// FailFast policy, the returned slice holds the elements collected before the error;
+// for ordered streams, these are exactly the elements before the failing one in
+// encounter order.
+func (s Stream[T]) ToSliceErr() ([]T, error) {
+	res := s.failFast().ToSlice()
+	return res, s.pipe.err()
+}
+
+// ForEachErr invokes the consumer function for each item of the stream. Errors
+// returned by the consumer are handled like the errors of the error-aware operators
+// of the pipeline, according to its ErrorPolicy, and returned along with them.
+// This function is equivalent to invoking input.ForEachErr(consumer) as method.
+func ForEachErr[T any](input Stream[T], consumer func(T) error) error {
+	return input.ForEachErr(consumer)
+}
+
+func (s Stream[T]) ForEachErr(consumer func(T) error) error {
+	s.failFast().ForEach(func(v T) {
+		if err := consumer(v); err != nil {
+			s.pipe.report(err)
+		}
+	})
+	return s.pipe.err()
+}
@@ -0,0 +1,159 @@
+package stream
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"sort"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"testing"
+)
+
+func TestMapErr(t *testing.T) {
+	tests := []struct {
+		name    string
+		s       Stream[string]
+		policy  ErrorPolicy
+		want    []int
+		wantErr int // number of errors joined in the returned error
+	}{
+		{
+			name:    "no errors",
+			s:       Of("1", "2", "3"),
+			policy:  FailFast,
+			want:    []int{1, 2, 3},
+			wantErr: 0,
+		},
+		{
+			name:    "fail fast",
+			s:       Of("1", "x", "3", "y"),
+			policy:  FailFast,
+			want:    []int{1},
+			wantErr: 1,
+		},
+		{
+			name:    "skip and collect",
+			s:       Of("1", "x", "3", "y"),
+			policy:  SkipAndCollect,
+			want:    []int{1, 3},
+			wantErr: 2,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := MapErr(tt.s.WithErrorPolicy(tt.policy), strconv.Atoi).ToSliceErr()
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("MapErr().ToSliceErr() = %v, want %v", got, tt.want)
+			}
+			if n := countErrors(err); n != tt.wantErr {
+				t.Errorf("MapErr().ToSliceErr() error = %v, want %d errors", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestMapErr_ParallelFailFast(t *testing.T) {
+	pulled := int64(0)
+	boom := errors.New("boom")
+	_, err := MapErr(Generate(func() (int, bool) {
+		return int(atomic.AddInt64(&pulled, 1)), true
+	}).Parallel(8), func(i int) (int, error) {
+		if i == 100 {
+			return 0, boom
+		}
+		return i, nil
+	}).ToSliceErr()
+	if !errors.Is(err, boom) {
+		t.Errorf("MapErr().ToSliceErr() error = %v, want %v", err, boom)
+	}
+}
+
+func TestMapErr_RouteErrors(t *testing.T) {
+	var mu sync.Mutex
+	var routed []string
+	got, err := MapErr(Of("1", "x", "3", "y").Parallel(2).WithErrorPolicy(RouteErrors(func(err error) {
+		mu.Lock()
+		defer mu.Unlock()
+		routed = append(routed, err.Error())
+	})), strconv.Atoi).ToSliceErr()
+	sort.Ints(got)
+	if err != nil || !reflect.DeepEqual(got, []int{1, 3}) {
+		t.Errorf("MapErr().ToSliceErr() = %v, %v, want %v, nil", got, err, []int{1, 3})
+	}
+	if len(routed) != 2 {
+		t.Errorf("routed errors = %v, want 2 errors", routed)
+	}
+}
+
+func TestFilterErr(t *testing.T) {
+	isEven := func(i int) (bool, error) {
+		if i < 0 {
+			return false, fmt.Errorf("negative number %d", i)
+		}
+		return i%2 == 0, nil
+	}
+	got, err := Of(1, 2, -3, 4).WithErrorPolicy(SkipAndCollect).FilterErr(isEven).ToSliceErr()
+	if !reflect.DeepEqual(got, []int{2, 4}) || countErrors(err) != 1 {
+		t.Errorf("Stream.FilterErr().ToSliceErr() = %v, %v, want %v and 1 error", got, err, []int{2, 4})
+	}
+	got, err = Of(1, 2, -3, 4).FilterErr(isEven).ToSliceErr()
+	if !reflect.DeepEqual(got, []int{2}) || countErrors(err) != 1 {
+		t.Errorf("Stream.FilterErr().ToSliceErr() = %v, %v, want %v and 1 error", got, err, []int{2})
+	}
+}
+
+func TestFlatMapErr(t *testing.T) {
+	repeat := func(s string) (Stream[string], error) {
+		n, err := strconv.Atoi(s[1:])
+		if err != nil {
+			return Stream[string]{}, err
+		}
+		return Generate(func() (string, bool) { return s[:1], true }).Limit(n), nil
+	}
+	got, err := FlatMapErr(Of("a2", "bx", "c1").WithErrorPolicy(SkipAndCollect), repeat).ToSliceErr()
+	if !reflect.DeepEqual(got, []string{"a", "a", "c"}) || countErrors(err) != 1 {
+		t.Errorf("FlatMapErr().ToSliceErr() = %v, %v, want %v and 1 error", got, err, []string{"a", "a", "c"})
+	}
+}
+
+func TestStream_ForEachErr(t *testing.T) {
+	boom := errors.New("boom")
+	visited := []int{}
+	err := Of(1, 2, 3, 4).ForEachErr(func(i int) error {
+		visited = append(visited, i)
+		if i == 2 {
+			return boom
+		}
+		return nil
+	})
+	if !errors.Is(err, boom) || !reflect.DeepEqual(visited, []int{1, 2}) {
+		t.Errorf("Stream.ForEachErr() = %v, visited %v, want %v, visited %v", err, visited, boom, []int{1, 2})
+	}
+}
+
+func TestConcat_Errors(t *testing.T) {
+	s1 := MapErr(Of("1", "x"), strconv.Atoi)
+	s2 := MapErr(Of("y", "4"), strconv.Atoi)
+	got, err := Concat(s1, s2).WithErrorPolicy(SkipAndCollect).ToSliceErr()
+	if !reflect.DeepEqual(got, []int{1, 4}) || countErrors(err) != 2 {
+		t.Errorf("Concat().ToSliceErr() = %v, %v, want %v and 2 errors", got, err, []int{1, 4})
+	}
+}
+
+// countErrors returns the number of non-joined errors wrapped by err.
+func countErrors(err error) int {
+	if err == nil {
+		return 0
+	}
+	joined, ok := err.(interface{ Unwrap() []error })
+	if !ok {
+		return 1
+	}
+	n := 0
+	for _, e := range joined.Unwrap() {
+		n += countErrors(e)
+	}
+	return n
+}
@@ -0,0 +1,263 @@
+package stream
+
+import (
+	"bufio"
+	"encoding/gob"
+	"errors"
+	"io"
+	"os"
+	"sync"
+)
+
+// DefaultRunSize is the number of elements sorted in memory at once by
+// SortedExternal when ExternalSortOptions.RunSize is not set.
+const DefaultRunSize = 1 << 20
+
+// DefaultMaxOpenRuns is the number of temporary files read at once by SortedExternal
+// when ExternalSortOptions.MaxOpenRuns is not set.
+const DefaultMaxOpenRuns = 64
+
+// externalSortBuffer is the number of elements buffered between the goroutines of a
+// parallel stream and the goroutine filling the runs of SortedExternal.
+const externalSortBuffer = 256
+
+// Codec encodes the elements that SortedExternal spills to disk, and decodes them
+// back. Both functions are invoked once per temporary file, and return a function
+// encoding or decoding the elements one after the other, so a codec can keep state
+// per file (e.g. the type information of gob). The decoding function returns io.EOF
+// after the last element.
+type Codec[T any] struct {
+	NewEncoder func(w io.Writer) func(v T) error
+	NewDecoder func(r io.Reader) func() (T, error)
+}
+
+// GobCodec returns a Codec encoding the elements with encoding/gob, which handles
+// most types with exported fields.
+func GobCodec[T any]() Codec[T] {
+	return Codec[T]{
+		NewEncoder: func(w io.Writer) func(T) error {
+			enc := gob.NewEncoder(w)
+			return func(v T) error { return enc.Encode(v) }
+		},
+		NewDecoder: func(r io.Reader) func() (T, error) {
+			dec := gob.NewDecoder(r)
+			return func() (T, error) {
+				var v T
+				err := dec.Decode(&v)
+				return v, err
+			}
+		},
+	}
+}
+
+// ExternalSortOptions configures SortedExternal.
+type ExternalSortOptions struct {
+	// RunSize is the number of elements sorted in memory at once, DefaultRunSize if
+	// not positive. It bounds the memory used by the sort.
+	RunSize int
+	// MaxOpenRuns is the number of temporary files merged at once, DefaultMaxOpenRuns
+	// if less than 2. It bounds the file descriptors used by the sort.
+	MaxOpenRuns int
+	// TempDir is the directory of the temporary files, os.TempDir() if empty.
+	TempDir string
+}
+
+// SortedExternal returns a stream consisting of the elements of this stream, sorted
+// according to the provided Comparator, without holding all of them in memory. The
+// elements are sorted in runs of opts.RunSize elements, as Sorted does, and the runs
+// are spilled to temporary files with codec. The runs are then merged lazily as the
+// sorted stream is pulled, holding one element of each run in memory. When there
+// are more than opts.MaxOpenRuns runs, groups of that many runs are first merged
+// into bigger runs, so at most opts.MaxOpenRuns files are open at once. A stream that
+// fits in a single run is sorted in memory, without any file. Like Sorted, the
+// returned stream is ordered and keeps the parallelism of this stream.
+//
+// The sort happens on the first pull. The temporary files are removed as soon as
+// they are merged, or when the stream is closed. I/O and codec errors are reported
+// to the pipeline and end the stream, see ToSliceErr and ForEachErr.
+// This function is equivalent to invoking s.SortedExternal(comparator, codec, opts)
+// as method.
+func SortedExternal[T any](s Stream[T], comparator Comparator[T], codec Codec[T], opts ExternalSortOptions) Stream[T] {
+	return s.SortedExternal(comparator, codec, opts)
+}
+
+func (s Stream[T]) SortedExternal(comparator Comparator[T], codec Codec[T], opts ExternalSortOptions) Stream[T] {
+	if opts.RunSize <= 0 {
+		opts.RunSize = DefaultRunSize
+	}
+	if opts.MaxOpenRuns < 2 {
+		opts.MaxOpenRuns = DefaultMaxOpenRuns
+	}
+	es := &externalSort[T]{s: s, cmp: comparator, codec: codec, opts: opts, files: make(map[string]*os.File)}
+	s.pipe.onClose(es.cleanup)
+	var once sync.Once
+	var merged Stream[T]
+	return Stream[T]{
+		config: config{parallel: s.parallel, ordered: true, pipe: s.pipe, clock: s.clock},
+		nextFn: func() (T, bool) {
+			once.Do(func() {
+				runs, err := es.sortRuns()
+				if err != nil {
+					s.pipe.report(err)
+					runs = nil
+				}
+				merged = MergeSorted(comparator, runs...)
+			})
+			return merged.nextFn()
+		},
+	}
+}
+
+// externalSort holds the state of SortedExternal.
+type externalSort[T any] struct {
+	s     Stream[T]
+	cmp   Comparator[T]
+	codec Codec[T]
+	opts  ExternalSortOptions
+
+	mu sync.Mutex
+	// files holds the run files not removed yet, with the open file of the ones
+	// being read.
+	files map[string]*os.File
+}
+
+// sortRuns sorts the input stream in runs, spills all of them but the last one to
+// temporary files, merges the files in several passes if there are more than
+// MaxOpenRuns of them, and returns a stream over each remaining run.
+func (es *externalSort[T]) sortRuns() ([]Stream[T], error) {
+	input := es.s.Sequential(externalSortBuffer)
+	var paths []string
+	var last []T
+	for {
+		run := make([]T, 0, min(es.opts.RunSize, 1<<16))
+		for len(run) < es.opts.RunSize {
+			v, hasNext := input.nextFn()
+			if !hasNext {
+				break
+			}
+			run = append(run, v)
+		}
+		parallelMergeSort(run, es.cmp, es.s.parallel, false)
+		if len(run) < es.opts.RunSize {
+			// the last run stays in memory
+			last = run
+			break
+		}
+		path, err := es.spill(OfSlice(run).nextFn)
+		if err != nil {
+			return nil, err
+		}
+		paths = append(paths, path)
+	}
+	for len(paths) > es.opts.MaxOpenRuns {
+		group := make([]Stream[T], es.opts.MaxOpenRuns)
+		var readErr error
+		for i, path := range paths[:len(group)] {
+			group[i] = es.read(path, func(err error) { readErr = errors.Join(readErr, err) })
+		}
+		path, err := es.spill(MergeSorted(es.cmp, group...).nextFn)
+		if err = errors.Join(err, readErr); err != nil {
+			return nil, err
+		}
+		paths = append(paths[len(group):], path)
+	}
+	runs := make([]Stream[T], 0, len(paths)+1)
+	for _, path := range paths {
+		runs = append(runs, es.read(path, func(err error) { es.s.pipe.report(err) }))
+	}
+	return append(runs, OfSlice(last)), nil
+}
+
+// spill writes the sorted elements returned by next to a temporary file, which is
+// closed afterwards, and returns its path.
+func (es *externalSort[T]) spill(next func() (T, bool)) (string, error) {
+	f, err := os.CreateTemp(es.opts.TempDir, "stream-sort-*")
+	if err != nil {
+		return "", err
+	}
+	es.mu.Lock()
+	es.files[f.Name()] = nil
+	es.mu.Unlock()
+	w := bufio.NewWriter(f)
+	encode := es.codec.NewEncoder(w)
+	for v, hasNext := next(); hasNext; v, hasNext = next() {
+		if err := encode(v); err != nil {
+			return "", errors.Join(err, f.Close())
+		}
+	}
+	if err := w.Flush(); err != nil {
+		return "", errors.Join(err, f.Close())
+	}
+	return f.Name(), f.Close()
+}
+
+// read returns a stream of the elements of a run file, which is opened on the first
+// pull, and removed once exhausted. The stream ends at the first error, which is
+// passed to onErr.
+func (es *externalSort[T]) read(path string, onErr func(error)) Stream[T] {
+	var decode func() (T, error)
+	done := false
+	return Generate(func() (T, bool) {
+		var zeroVal T
+		if done {
+			return zeroVal, false
+		}
+		if decode == nil {
+			f, err := os.Open(path)
+			if err != nil {
+				done = true
+				onErr(err)
+				return zeroVal, false
+			}
+			es.mu.Lock()
+			es.files[path] = f
+			es.mu.Unlock()
+			decode = es.codec.NewDecoder(bufio.NewReader(f))
+		}
+		v, err := decode()
+		if err == nil {
+			return v, true
+		}
+		done = true
+		if !errors.Is(err, io.EOF) {
+			onErr(err)
+		}
+		if err := es.remove(path); err != nil {
+			onErr(err)
+		}
+		return zeroVal, false
+	})
+}
+
+// remove closes and removes a run file.
+func (es *externalSort[T]) remove(path string) error {
+	es.mu.Lock()
+	f, ok := es.files[path]
+	delete(es.files, path)
+	es.mu.Unlock()
+	if !ok {
+		return nil
+	}
+	return errors.Join(closeFile(f), os.Remove(path))
+}
+
+// cleanup closes and removes the run files that were not merged yet.
+func (es *externalSort[T]) cleanup() error {
+	es.mu.Lock()
+	files := es.files
+	es.files = make(map[string]*os.File)
+	es.mu.Unlock()
+	var errs []error
+	for path, f := range files {
+		errs = append(errs, closeFile(f), os.Remove(path))
+	}
+	return errors.Join(errs...)
+}
+
+// closeFile closes f unless it is nil, i.e. the run file was not opened.
+func closeFile(f *os.File) error {
+	if f == nil {
+		return nil
+	}
+	return f.Close()
+}
@@ -0,0 +1,144 @@
+package stream
+
+import (
+	"errors"
+	"io"
+	"math/rand"
+	"os"
+	"reflect"
+	"sort"
+	"sync"
+	"testing"
+)
+
+func TestSortedExternal(t *testing.T) {
+	r := rand.New(rand.NewSource(1))
+	input := make([]int, 1050)
+	for i := range input {
+		input[i] = r.Intn(500)
+	}
+	want := append([]int{}, input...)
+	sort.Ints(want)
+
+	files := func(dir string) int {
+		entries, err := os.ReadDir(dir)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return len(entries)
+	}
+
+	for _, p := range []int{1, 4} {
+		dir := t.TempDir()
+		opts := ExternalSortOptions{RunSize: 100, TempDir: dir}
+		// Peek runs in p goroutines, the first one counts the run files
+		var once sync.Once
+		spilled := 0
+		s := OfSlice(input).Parallel(p).SortedExternal(Natural[int], GobCodec[int](), opts).
+			Peek(func(int) { once.Do(func() { spilled = files(dir) }) })
+		got, err := s.ToSliceErr()
+		if err != nil {
+			t.Fatalf("parallel %d: ToSliceErr() error = %v", p, err)
+		}
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("parallel %d: SortedExternal() = %v, want %v", p, got, want)
+		}
+		if spilled != 10 {
+			t.Errorf("parallel %d: %d runs spilled, want 10", p, spilled)
+		}
+		if n := files(dir); n != 0 {
+			t.Errorf("parallel %d: %d temporary files left", p, n)
+		}
+	}
+
+	t.Run("several merge passes", func(t *testing.T) {
+		dir := t.TempDir()
+		opts := ExternalSortOptions{RunSize: 100, MaxOpenRuns: 3, TempDir: dir}
+		remaining := -1
+		got := OfSlice(input).SortedExternal(Natural[int], GobCodec[int](), opts).
+			Peek(func(int) {
+				if remaining < 0 {
+					remaining = files(dir)
+				}
+			}).
+			ToSlice()
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("SortedExternal() = %v, want %v", got, want)
+		}
+		if remaining > 3 {
+			t.Errorf("%d runs merged at once, want at most 3", remaining)
+		}
+		if n := files(dir); n != 0 {
+			t.Errorf("%d temporary files left", n)
+		}
+	})
+
+	t.Run("ordered parallel upstream", func(t *testing.T) {
+		var slow inFlight
+		opts := ExternalSortOptions{RunSize: 100, TempDir: t.TempDir()}
+		got := Map(OfSlice(input[:300]).ParallelOrdered(4), slowly(&slow, func(n int) int { return n })).
+			SortedExternal(Natural[int], GobCodec[int](), opts).
+			ToSlice()
+		if !sort.IntsAreSorted(got) || len(got) != 300 {
+			t.Errorf("SortedExternal() = %v", got)
+		}
+		if slow.maximum() < 2 {
+			t.Errorf("Map() before SortedExternal() ran in %d goroutine(s), want several", slow.maximum())
+		}
+	})
+
+	t.Run("early close", func(t *testing.T) {
+		dir := t.TempDir()
+		opts := ExternalSortOptions{RunSize: 100, TempDir: dir}
+		got := OfSlice(input).SortedExternal(Natural[int], GobCodec[int](), opts).Limit(3).ToSlice()
+		if !reflect.DeepEqual(got, want[:3]) {
+			t.Errorf("SortedExternal() = %v, want %v", got, want[:3])
+		}
+		if n := files(dir); n != 0 {
+			t.Errorf("%d temporary files left", n)
+		}
+	})
+
+	t.Run("in memory", func(t *testing.T) {
+		dir := t.TempDir()
+		opts := ExternalSortOptions{TempDir: dir}
+		got := SortedExternal(Of(3, 1, 2), Natural[int], GobCodec[int](), opts).ToSlice()
+		if !reflect.DeepEqual(got, []int{1, 2, 3}) {
+			t.Errorf("SortedExternal() = %v", got)
+		}
+	})
+
+	t.Run("decode error in a merge pass", func(t *testing.T) {
+		errDecode := errors.New("decode failed")
+		codec := GobCodec[int]()
+		codec.NewDecoder = func(io.Reader) func() (int, error) {
+			return func() (int, error) { return 0, errDecode }
+		}
+		dir := t.TempDir()
+		opts := ExternalSortOptions{RunSize: 100, MaxOpenRuns: 2, TempDir: dir}
+		_, err := OfSlice(input).SortedExternal(Natural[int], codec, opts).ToSliceErr()
+		if !errors.Is(err, errDecode) {
+			t.Errorf("ToSliceErr() error = %v, want %v", err, errDecode)
+		}
+		if n := files(dir); n != 0 {
+			t.Errorf("%d temporary files left", n)
+		}
+	})
+
+	t.Run("codec error", func(t *testing.T) {
+		errEncode := errors.New("encode failed")
+		codec := GobCodec[int]()
+		codec.NewEncoder = func(io.Writer) func(int) error {
+			return func(int) error { return errEncode }
+		}
+		dir := t.TempDir()
+		opts := ExternalSortOptions{RunSize: 10, TempDir: dir}
+		_, err := OfSlice(input).SortedExternal(Natural[int], codec, opts).ToSliceErr()
+		if !errors.Is(err, errEncode) {
+			t.Errorf("ToSliceErr() error = %v, want %v", err, errEncode)
+		}
+		if n := files(dir); n != 0 {
+			t.Errorf("%d temporary files left", n)
+		}
+	})
+}
@@ -0,0 +1,51 @@
+package stream
+
+import (
+	"sync"
+	"sync/atomic"
+)
+
+// GroupBy groups the elements of the stream by the key returned by keyFn. The
+// elements of every group keep the encounter order for sequential and ordered
+// streams. For parallel streams each goroutine groups its elements in its own map,
+// and the maps are merged at the end.
+func GroupBy[T any, K comparable](s Stream[T], keyFn func(T) K) map[K][]T {
+	return Collect(s, GroupingBy(keyFn, ToList[T]()))
+}
+
+// GroupByStream returns a stream of the groups of the elements of the input stream,
+// by the key returned by keyFn. Every group is a pair of the key and a stream of the
+// elements with that key. The input stream is fully grouped, like by GroupBy, when
+// the first group is pulled; the groups are then delivered in no particular order,
+// so the returned stream is not ordered even if the input stream is. The errors
+// reported to the pipeline of the input stream are visible from the group streams,
+// which are closed along with the returned stream.
+func GroupByStream[T any, K comparable](s Stream[T], keyFn func(T) K) Stream[Pair[K, Stream[T]]] {
+	var groups []Pair[K, Stream[T]]
+	doGroup := func() {
+		for k, vals := range collect(s, GroupingBy(keyFn, ToList[T]())) {
+			group := OfSlice(vals)
+			group.pipe = newPipeline()
+			group.pipe.upstream = s.pipe
+			group.clock = s.clock
+			s.pipe.onClose(group.pipe.close)
+			groups = append(groups, PairOf(k, group))
+		}
+	}
+	once := sync.Once{}
+	index := int64(0)
+	c := s.config
+	c.ordered = false
+	return Stream[Pair[K, Stream[T]]]{
+		config: c,
+		nextFn: func() (Pair[K, Stream[T]], bool) {
+			once.Do(doGroup)
+			index := atomic.AddInt64(&index, 1)
+			if index > int64(len(groups)) {
+				var zeroVal Pair[K, Stream[T]]
+				return zeroVal, false
+			}
+			return groups[index-1], true
+		},
+	}
+}
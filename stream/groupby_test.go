@@ -0,0 +1,98 @@
+package stream
+
+import (
+	"errors"
+	"reflect"
+	"sort"
+	"testing"
+)
+
+func TestGroupBy(t *testing.T) {
+	mod3 := func(i int) int { return i % 3 }
+	tests := []struct {
+		name string
+		s    Stream[int]
+		want map[int][]int
+	}{
+		{
+			name: "sequential",
+			s:    Of(1, 2, 3, 4, 5, 6, 7),
+			want: map[int][]int{0: {3, 6}, 1: {1, 4, 7}, 2: {2, 5}},
+		},
+		{
+			name: "parallel",
+			s:    Range(1, 8).Parallel(4),
+			want: map[int][]int{0: {3, 6}, 1: {1, 4, 7}, 2: {2, 5}},
+		},
+		{
+			name: "large parallel",
+			s:    Range(0, 10000).Parallel(8),
+			want: Collect(Range(0, 10000), GroupingBy(mod3, ToList[int]())),
+		},
+		{
+			name: "empty",
+			s:    Of[int](),
+			want: map[int][]int{},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := GroupBy(tt.s, mod3)
+			for _, vals := range got {
+				sort.Ints(vals)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("GroupBy() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestGroupByStream(t *testing.T) {
+	isEven := func(i int) bool { return i%2 == 0 }
+	sum := func(a, b int) int { return a + b }
+	for _, p := range []int{1, 4} {
+		groups := GroupByStream(Range(0, 100).Parallel(p), isEven).ToSlice()
+		got := map[bool]int{}
+		for _, group := range groups {
+			got[group.First] = group.Second.Reduce(0, sum)
+		}
+		if want := map[bool]int{true: 2450, false: 2500}; !reflect.DeepEqual(got, want) {
+			t.Errorf("parallel %d: sums of GroupByStream() = %v, want %v", p, got, want)
+		}
+	}
+	count := GroupByStream(Range(0, 100).Parallel(4), func(i int) int { return i % 7 }).Count()
+	if count != 7 {
+		t.Errorf("GroupByStream().Count() = %d, want 7", count)
+	}
+}
+
+func TestGroupByStream_Pipeline(t *testing.T) {
+	if GroupByStream(Range(0, 10).ParallelOrdered(4), func(i int) int { return i % 3 }).ordered {
+		t.Error("GroupByStream() of an ordered stream is ordered")
+	}
+
+	errOdd := errors.New("odd")
+	input := MapErr(Range(0, 10), func(i int) (int, error) {
+		if i%2 == 1 {
+			return 0, errOdd
+		}
+		return i, nil
+	}).WithErrorPolicy(SkipAndCollect)
+	groups := GroupByStream(input, func(i int) bool { return i < 5 })
+	group, ok := groups.nextFn()
+	if !ok {
+		t.Fatal("GroupByStream() has no group")
+	}
+	closed := false
+	group.Second = group.Second.OnClose(func() error { closed = true; return nil })
+	if err := groups.Close(); err != nil {
+		t.Errorf("Close() error = %v", err)
+	}
+	if !closed {
+		t.Error("group stream not closed along with GroupByStream()")
+	}
+	if _, err := group.Second.ToSliceErr(); !errors.Is(err, errOdd) {
+		t.Errorf("group ToSliceErr() error = %v, want %v", err, errOdd)
+	}
+}
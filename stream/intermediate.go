@@ -1,75 +1,206 @@
 package stream
 
 import (
-	"fmt"
+	"context"
+	"errors"
+	"sort"
 	"sync"
 	"sync/atomic"
+
+	"golang.org/x/exp/constraints"
 )
 
-func (s stream[T]) Parallel(p int) stream[T] {
-	return stream[T]{
-		parallel: max(p, 1),
-		nextFn:   s.nextFn,
+// Parallel returns a stream whose terminal and stateful operations use p goroutines
+// to consume it. Unless the stream is ordered (see Ordered), the encounter order of
+// the elements is not preserved.
+func (s Stream[T]) Parallel(p int) Stream[T] {
+	c := s.config
+	c.parallel = max(p, 1)
+	if s.seqFn != nil {
+		return fromSequenced(c, s.seqFn)
+	}
+	return Stream[T]{
+		config: c,
+		nextFn: s.nextFn,
+		split:  s.split,
+	}
+}
+
+// Parallelism returns the number of goroutines that terminal and stateful
+// operations will use to consume this stream.
+func (s Stream[T]) Parallelism() int {
+	return s.parallel
+}
+
+// WithContext returns a stream that stops as soon as ctx is done: once ctx is
+// cancelled or its deadline expires, every pull from the returned stream reports
+// the end of the stream, so all the goroutines of the downstream terminal operation
+// stop pulling and return. Elements pulled before the cancellation are kept.
+// Cancellation is observed between pulls, so a stage blocked inside a single pull
+// (e.g. an OfChannel source waiting for a value) returns only once that pull does;
+// placing WithContext right after the source gives the quickest reaction.
+// Use the Ctx variants of the terminal operations (ForEachCtx, ToSliceCtx,
+// ReduceCtx...) to also learn whether the stream was cut short.
+func (s Stream[T]) WithContext(ctx context.Context) Stream[T] {
+	cs, _ := cancelable(s, ctx)
+	return cs
+}
+
+// cancelable returns a stream that ends when ctx is done, along with a function
+// returning ctx.Err() if the cancellation cut the stream short, or nil otherwise.
+func cancelable[T any](s Stream[T], ctx context.Context) (Stream[T], func() error) {
+	done := ctx.Done()
+	if done == nil {
+		// the context can never be cancelled
+		return s, func() error { return nil }
+	}
+	cut := int32(0)
+	cancelled := func() bool {
+		select {
+		case <-done:
+			atomic.StoreInt32(&cut, 1)
+			return true
+		default:
+			return false
+		}
 	}
+	err := func() error {
+		if atomic.LoadInt32(&cut) == 1 {
+			return ctx.Err()
+		}
+		return nil
+	}
+	return stateless(s, func(next func() (T, bool)) func() (T, bool) {
+		return func() (T, bool) {
+			var zeroVal T
+			if cancelled() {
+				return zeroVal, false
+			}
+			v, hasNext := next()
+			if !hasNext || cancelled() {
+				return zeroVal, false
+			}
+			return v, true
+		}
+	}), err
+}
+
+// OnClose returns the stream with handler registered to be invoked when the stream
+// is closed. Handlers are shared by all the stages of a pipeline, so a handler
+// registered on a source (e.g. to close a file or sql.Rows) runs when a terminal
+// operation on any stream derived from it with Map, Filter, FlatMap, Concat... is
+// done, whether it consumed all the elements, stopped early (AnyMatch, Limit,
+// FindFirst...) or panicked. Handlers run once, in reverse order of registration.
+// Their errors are returned by Close and by the error-aware terminals (ToSliceErr,
+// ForEachErr).
+func (s Stream[T]) OnClose(handler func() error) Stream[T] {
+	s.pipe.onClose(handler)
+	return s
 }
 
-func (s stream[T]) Take(n int) stream[T] {
-	return stream[T]{
-		parallel: s.parallel,
+// Take returns a stream consisting of at most the first n elements of this stream.
+// For parallel streams the n elements are the first n pulled by any of the goroutines,
+// which are not necessarily the first n in encounter order.
+func (s Stream[T]) Take(n int) Stream[T] {
+	if s.limit != nil && n > 0 {
+		return s.limit(n)
+	}
+	remaining := int64(n)
+	return Stream[T]{
+		config: s.config,
 		nextFn: func() (T, bool) {
-			if n <= 0 {
+			if atomic.LoadInt64(&remaining) <= 0 || atomic.AddInt64(&remaining, -1) < 0 {
 				var zeroVal T
 				return zeroVal, false
 			}
-			n--
 			return s.nextFn()
 		},
 	}
 }
 
-// Sequential currently is not that much useful, supress it for now
-func (s stream[T]) _Sequential(bufSize int) stream[T] {
-	if s.parallel <= 1 {
-		return s
+// Sequential returns a stream that funnels the elements of a parallel stream into a
+// single goroutine, so the following stages (e.g. a Peek writing to a bufio.Writer)
+// and the terminal operation are invoked sequentially. The stages before Sequential
+// keep running in Parallelism() goroutines, which send their elements through a
+// channel of bufSize elements and block while it is full.
+// The goroutines are started on the first pull and stopped as soon as the terminal
+// operation is done, so a downstream Limit or AnyMatch stops the upstream stages too.
+// A panic in one of them is re-raised on the goroutine pulling from the stream.
+// For sequential streams, it is equivalent to Parallel(1). Ordered streams already
+// deliver their elements from a single goroutine, through the buffer that restores
+// the encounter order: their stages before Sequential keep running in parallel, and
+// only the following ones run sequentially.
+func (s Stream[T]) Sequential(bufSize int) Stream[T] {
+	if s.ordered {
+		c := s.config
+		c.parallel = 1
+		// the pull function of the elements tagged with their position is left out,
+		// as pulling it directly would bypass the parallel stages
+		return Stream[T]{config: c, nextFn: s.nextFn}
 	}
-	resCh := make(chan T, bufSize)
-	countDown := int32(s.parallel)
-	doParallel := func() {
-		for i := 0; i < s.parallel; i++ {
-			go func() {
-				defer func() {
-					if atomic.AddInt32(&countDown, -1) <= 0 {
-						close(resCh)
-					}
-				}()
+	if !s.concurrent() {
+		return s.Parallel(1)
+	}
+	var once sync.Once
+	var resCh chan T
+	var perr *PanicError
+	done := make(chan struct{})
+	start := func() {
+		resCh = make(chan T, max(bufSize, 0))
+		s.pipe.onClose(func() error { close(done); return nil })
+		go func() {
+			perr = s.runParallel(func(next func() (T, bool)) {
 				for {
-					v, hasNext := s.nextFn()
+					select {
+					case <-done:
+						return
+					default:
+					}
+					v, hasNext := next()
 					if !hasNext {
 						return
 					}
-					resCh <- v
+					select {
+					case resCh <- v:
+					case <-done:
+						return
+					}
 				}
-			}()
-		}
+			})
+			close(resCh)
+		}()
 	}
-	// fmt.Println("seq parallel:", s.parallel)
-	// debug.PrintStack()
-	go doParallel()
-	return stream[T]{
-		parallel: 1,
+	return Stream[T]{
+		config: config{parallel: 1, pipe: s.pipe, clock: s.clock},
 		nextFn: func() (T, bool) {
+			once.Do(start)
 			v, ok := <-resCh
+			if !ok && perr != nil {
+				panic(perr)
+			}
 			return v, ok
 		},
 	}
 }
 
-func (s stream[T]) Filter(predicate func(T) bool) stream[T] {
-	return stream[T]{
-		parallel: s.parallel,
-		nextFn: func() (T, bool) {
+// Filter returns a stream consisting of the elements of this stream that match the
+// given predicate. For parallel streams the predicate is invoked concurrently.
+func (s Stream[T]) Filter(predicate func(T) bool) Stream[T] {
+	if s.ordered {
+		return mapSequenced(s, func(vals []T) []T {
+			kept := vals[:0]
+			for _, v := range vals {
+				if predicate(v) {
+					kept = append(kept, v)
+				}
+			}
+			return kept
+		})
+	}
+	return stateless(s, func(next func() (T, bool)) func() (T, bool) {
+		return func() (T, bool) {
 			for {
-				v, hasNext := s.nextFn()
+				v, hasNext := next()
 				if !hasNext {
 					return v, false
 				}
@@ -77,19 +208,22 @@ func (s stream[T]) Filter(predicate func(T) bool) stream[T] {
 					return v, true
 				}
 			}
-		},
-	}
+		}
+	})
 }
 
-func (s stream[T]) FilterN(n int, predicate func(T) bool) stream[T] {
+// FilterN returns a stream consisting of at most n elements of this stream that
+// match the given predicate. Like Take, for parallel streams the n elements are
+// the first ones found by any of the goroutines.
+func (s Stream[T]) FilterN(n int, predicate func(T) bool) Stream[T] {
 	var zeroVal T
-	return stream[T]{
-		parallel: s.parallel,
+	remaining := int64(n)
+	return Stream[T]{
+		config: s.config,
 		nextFn: func() (T, bool) {
-			if n <= 0 {
+			if atomic.LoadInt64(&remaining) <= 0 || atomic.AddInt64(&remaining, -1) < 0 {
 				return zeroVal, false
 			}
-			n--
 			for {
 				v, hasNext := s.nextFn()
 				if !hasNext {
@@ -103,47 +237,131 @@ func (s stream[T]) FilterN(n int, predicate func(T) bool) stream[T] {
 	}
 }
 
-func (s stream[T]) Map(fn func(T) T) stream[T] {
+func (s Stream[T]) Map(fn func(T) T) Stream[T] {
 	return Map[T, T](s, fn)
 }
 
-func Map[I any, O any](s stream[I], fn func(I) O) stream[O] {
-	return stream[O]{
-		parallel: s.parallel,
-		nextFn: func() (O, bool) {
-			v, hasNext := s.nextFn()
+// Map returns a stream consisting of the results of applying fn to the elements of
+// the input stream. For parallel streams fn is invoked concurrently.
+// When the input and output types are the same, the operation can be invoked as
+// the method input.Map(fn).
+func Map[I any, O any](s Stream[I], fn func(I) O) Stream[O] {
+	if s.ordered {
+		return mapSequenced(s, func(vals []I) []O {
+			out := make([]O, len(vals))
+			for i, v := range vals {
+				out[i] = fn(v)
+			}
+			return out
+		})
+	}
+	return stateless(s, func(next func() (I, bool)) func() (O, bool) {
+		return func() (O, bool) {
+			v, hasNext := next()
 			var zeroVal O
 			if !hasNext {
 				return zeroVal, false
 			}
 			return fn(v), true
-		},
+		}
+	})
+}
+
+// Transform returns a stream whose pull function is built by op from the pull
+// function of the input stream. It is the extension point for writing operators
+// outside this package: op receives next, which returns the next element of the
+// input stream along with true, or the zero value along with false once the input
+// is exhausted, and must return a pull function with the same contract.
+// The resulting stream keeps the parallelism of the input stream, so when the
+// stream is parallel the returned pull function is invoked concurrently from
+// several goroutines and any state it captures must be synchronized.
+//
+//	func Every[T any](s stream.Stream[T], n int) stream.Stream[T] {
+//		return stream.Transform(s, func(next func() (T, bool)) func() (T, bool) {
+//			return func() (T, bool) {
+//				for i := 1; i < n; i++ {
+//					if _, ok := next(); !ok {
+//						var zero T
+//						return zero, false
+//					}
+//				}
+//				return next()
+//			}
+//		})
+//	}
+func Transform[I, O any](input Stream[I], op func(next func() (I, bool)) func() (O, bool)) Stream[O] {
+	return Stream[O]{
+		config: input.config,
+		nextFn: op(input.nextFn),
 	}
 }
 
 // Limit returns a stream consisting of the elements of this stream, truncated to
-// be no longer than maxSize in length.
+// be no longer than maxSize in length. Applied right after Sorted, it is rewritten
+// into BottomKStream, so only maxSize elements are held in memory instead of all of
+// them.
 // This function is equivalent to invoking input.Limit(maxSize) as method.
-func Limit[T any](s stream[T], n int) stream[T] {
+func Limit[T any](s Stream[T], n int) Stream[T] {
 	return s.Take(n)
 }
 
-func (s stream[T]) Limit(maxSize int) stream[T] {
+func (s Stream[T]) Limit(maxSize int) Stream[T] {
 	return s.Take(maxSize)
 }
 
-func Distinct[T comparable](s stream[T]) stream[T] {
-	register := make(map[T]struct{})
-	return stream[T]{
-		parallel: s.parallel,
+// Distinct returns a stream consisting of the distinct elements of the input stream.
+// The set of already seen elements is guarded by a lock, so it can be shared by the
+// goroutines of a parallel stream; each element is emitted exactly once, by the first
+// goroutine that pulls it. The set grows with every distinct element, use DistinctLRU
+// to bound the memory used on infinite streams.
+func Distinct[T comparable](s Stream[T]) Stream[T] {
+	return DistinctBy(s, func(v T) T { return v })
+}
+
+// DistinctBy returns a stream consisting of the elements of the input stream with
+// distinct keys, as returned by keyFn, so the elements themselves don't need to be
+// comparable. Of the elements with the same key, only the first one pulled is
+// emitted. It works as Distinct for parallel streams.
+func DistinctBy[T any, K comparable](s Stream[T], keyFn func(T) K) Stream[T] {
+	var lock sync.Mutex
+	register := make(map[K]struct{})
+	return distinct(s, func(v T) bool {
+		k := keyFn(v)
+		lock.Lock()
+		defer lock.Unlock()
+		if _, ok := register[k]; ok {
+			return true
+		}
+		register[k] = struct{}{}
+		return false
+	})
+}
+
+// DistinctLRU is like Distinct, but it only remembers the capacity elements (at
+// least 1) seen the most recently, so it uses bounded memory on infinite streams.
+// An element is emitted again if it was forgotten since it was last seen; seeing
+// an element again, even if it is not emitted, makes it the most recent.
+func DistinctLRU[T comparable](s Stream[T], capacity int) Stream[T] {
+	var lock sync.Mutex
+	seen := newLRUSet[T](max(capacity, 1))
+	return distinct(s, func(v T) bool {
+		lock.Lock()
+		defer lock.Unlock()
+		return seen.touch(v)
+	})
+}
+
+// distinct returns the stream of the elements of s for which seen returns false.
+func distinct[T any](s Stream[T], seen func(T) bool) Stream[T] {
+	return Stream[T]{
+		config: s.config,
 		nextFn: func() (T, bool) {
 			for {
 				v, hasNext := s.nextFn()
 				if !hasNext {
 					return v, false
 				}
-				if _, ok := register[v]; !ok {
-					register[v] = struct{}{}
+				if !seen(v) {
 					return v, true
 				}
 			}
@@ -151,85 +369,194 @@ func Distinct[T comparable](s stream[T]) stream[T] {
 	}
 }
 
+// DistinctUntilChanged returns a stream consisting of the elements of the input
+// stream that differ from the previous element, so runs of equal elements are
+// collapsed to their first element. It only remembers the previous element. For
+// parallel streams the previous element is the one handled last by any goroutine, so
+// runs are only detected in encounter order for sequential and ordered streams.
+func DistinctUntilChanged[T comparable](s Stream[T]) Stream[T] {
+	return DistinctUntilChangedFunc(s, func(a, b T) bool { return a == b })
+}
+
+// DistinctUntilChangedFunc is like DistinctUntilChanged, but it compares consecutive
+// elements with eq.
+func DistinctUntilChangedFunc[T any](s Stream[T], eq func(a, b T) bool) Stream[T] {
+	var lock sync.Mutex
+	var prev T
+	started := false
+	return distinct(s, func(v T) bool {
+		lock.Lock()
+		defer lock.Unlock()
+		same := started && eq(prev, v)
+		prev, started = v, true
+		return same
+	})
+}
+
+// ParallelMergeSortThreshold is the length under which the parallel sort of Sorted
+// sorts a part of the elements, or merges two sorted parts, in a single goroutine.
 const ParallelMergeSortThreshold = 1 << 12
 
-// parallelInPlaceMergeSort sorts the given slice in-place using a parallel merge sort algorithm.
-// The algorithm uses the specified number of parallel goroutines to perform the merge sort.
-func parallelMergeSort[T any](slice []T, comparator Comparator[T], parallel int) {
-	if len(slice) <= ParallelMergeSortThreshold || parallel == 1 {
-		SortSlice(slice, comparator)
+// parallelMergeSort sorts the given slice in-place using a parallel merge sort
+// algorithm with up to the given number of goroutines: the two halves of the slice
+// are sorted concurrently, then merged concurrently through an auxiliary buffer.
+// Merging keeps the left elements first, so the sort is stable if stable is true.
+func parallelMergeSort[T any](slice []T, comparator Comparator[T], parallel int, stable bool) {
+	if len(slice) <= ParallelMergeSortThreshold || parallel <= 1 {
+		sortSequential(slice, comparator, stable)
 		return
 	}
+	mergeSort(slice, make([]T, len(slice)), comparator, parallel, stable)
+}
 
-	// Split the slice into two halves.
+// mergeSort sorts slice with up to parallel goroutines, using buf, of the same
+// length, as scratch space.
+func mergeSort[T any](slice, buf []T, comparator Comparator[T], parallel int, stable bool) {
+	if len(slice) <= ParallelMergeSortThreshold || parallel <= 1 {
+		sortSequential(slice, comparator, stable)
+		return
+	}
 	mid := len(slice) / 2
-	left := slice[:mid]
-	right := slice[mid:]
-
-	// Sort the two halves in parallel.
 	var wg sync.WaitGroup
 	wg.Add(1)
 	go func() {
 		defer wg.Done()
-		parallelMergeSort(left, comparator, parallel/2)
+		mergeSort(slice[:mid], buf[:mid], comparator, parallel/2, stable)
 	}()
-
-	parallelMergeSort(right, comparator, parallel/2)
+	mergeSort(slice[mid:], buf[mid:], comparator, parallel-parallel/2, stable)
 	wg.Wait()
-	fmt.Println("before merge:", slice, mid)
-	// Merge the two sorted halves in-place.
-	mergeSortedHalves(slice, mid, comparator)
+	if comparator(slice[mid-1], slice[mid]) <= 0 {
+		// the halves are already in order
+		return
+	}
+	copy(buf, slice)
+	parallelMerge(buf[:mid], buf[mid:], slice, comparator, parallel)
 }
 
-// // [1,2,3,4,5,1,2,3,4,5]
-// // merge two sorted parts into one sorted slice in place, the two parts are [0, mid) and [mid, len(slice))
-// mid is next of the last element of the first part
-func mergeSortedHalves[T any](slice []T, mid int, comparator Comparator[T]) {
-	if comparator(slice[mid-1], slice[mid]) <= 0 {
+func sortSequential[T any](slice []T, comparator Comparator[T], stable bool) {
+	if stable {
+		SortSliceStable(slice, comparator)
+	} else {
+		SortSlice(slice, comparator)
+	}
+}
+
+// parallelMerge merges the sorted slices a and b into dst, whose length is the sum
+// of theirs, with up to parallel goroutines. The elements of a come first among
+// equal elements.
+//
+// The larger input is split at its middle element, and the other one where that
+// element would be inserted, so the two lower parts and the two upper parts can be
+// merged independently.
+func parallelMerge[T any](a, b, dst []T, comparator Comparator[T], parallel int) {
+	if len(dst) <= ParallelMergeSortThreshold || parallel <= 1 {
+		merge(a, b, dst, comparator)
 		return
 	}
-	i, j, high := 0, mid, len(slice)
-	for i < mid && j < high {
-		if comparator(slice[i], slice[j]) <= 0 {
+	var i, j int
+	if len(a) >= len(b) {
+		i = len(a) / 2
+		// the elements of b equal to a[i] go after it
+		j = sort.Search(len(b), func(k int) bool { return comparator(b[k], a[i]) >= 0 })
+	} else {
+		j = len(b) / 2
+		// the elements of a equal to b[j] go before it
+		i = sort.Search(len(a), func(k int) bool { return comparator(a[k], b[j]) > 0 })
+	}
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		parallelMerge(a[:i], b[:j], dst[:i+j], comparator, parallel/2)
+	}()
+	parallelMerge(a[i:], b[j:], dst[i+j:], comparator, parallel-parallel/2)
+	wg.Wait()
+}
+
+// merge merges the sorted slices a and b into dst, the elements of a first among
+// equal elements.
+func merge[T any](a, b, dst []T, comparator Comparator[T]) {
+	i, j, k := 0, 0, 0
+	for i < len(a) && j < len(b) {
+		if comparator(a[i], b[j]) <= 0 {
+			dst[k] = a[i]
 			i++
 		} else {
-			tmp := slice[j]
-			copy(slice[i+1:], slice[i:j])
-			slice[i] = tmp
-			i++
+			dst[k] = b[j]
 			j++
-			mid++
 		}
+		k++
 	}
+	k += copy(dst[k:], a[i:])
+	copy(dst[k:], b[j:])
 }
 
 // Sorted returns a stream consisting of the elements of this stream, sorted according
-// to the provided Comparator.
+// to the provided Comparator. All the elements are pulled and sorted on the first
+// pull, in parallel if the stream is parallel. The returned stream is ordered (see
+// Ordered) and keeps the parallelism of this stream, so the stages that follow still
+// run in parallel while the elements reach the terminal operation in sorted order.
 // This function is equivalent to invoking s.Sorted(comparator) as method.
-func Sorted[T any](s stream[T], comparator Comparator[T]) stream[T] {
+func Sorted[T any](s Stream[T], comparator Comparator[T]) Stream[T] {
 	return s.Sorted(comparator)
 }
 
-func (s stream[T]) Sorted(comparator Comparator[T]) stream[T] {
-	var elems []T
-	doSort := func() {
-		elems = s.ToSlice()
-		if s.parallel > 1 {
-			parallelMergeSort(elems, comparator, s.parallel)
-		} else {
-			SortSlice(elems, comparator)
-		}
-		// fmt.Println("sorted:", elems)
+func (s Stream[T]) Sorted(comparator Comparator[T]) Stream[T] {
+	return sorted(s, comparator, false)
+}
+
+// SortedStable is like Sorted, but the elements that are equal according to the
+// comparator keep their relative order. For parallel streams that order is the
+// order in which the elements are pulled, so the result is only deterministic for
+// sequential and ordered streams.
+// This function is equivalent to invoking s.SortedStable(comparator) as method.
+func SortedStable[T any](s Stream[T], comparator Comparator[T]) Stream[T] {
+	return s.SortedStable(comparator)
+}
+
+func (s Stream[T]) SortedStable(comparator Comparator[T]) Stream[T] {
+	return sorted(s, comparator, true)
+}
+
+// SortedBy returns a stream consisting of the elements of this stream, sorted by the
+// natural order of the keys returned by keyFn. It is equivalent to
+// Sorted(s, Comparing(keyFn)); see Comparing and ThenComparingBy to sort by several
+// keys.
+func SortedBy[T any, K constraints.Ordered](s Stream[T], keyFn func(T) K) Stream[T] {
+	return s.Sorted(Comparing(keyFn))
+}
+
+func sorted[T any](s Stream[T], comparator Comparator[T], stable bool) Stream[T] {
+	out := lazySlice(s.config, func() []T {
+		elems := s.toSlice()
+		parallelMergeSort(elems, comparator, s.parallel, stable)
+		return elems
+	})
+	if !stable {
+		out.limit = func(n int) Stream[T] { return s.BottomKStream(n, comparator) }
 	}
+	return out
+}
+
+// lazySlice returns a stream of the elements of the slice returned by produce, which
+// is invoked on the first pull. The elements are in encounter order, so a parallel
+// stream is ordered, while the downstream stages keep the parallelism of c. A
+// sequential stream already keeps the order without the cost of an ordered one.
+func lazySlice[T any](c config, produce func() []T) Stream[T] {
+	var elems []T
 	once := sync.Once{}
+	doProduce := func() { elems = produce() }
 	index := int64(0)
-	return stream[T]{
-		// sorted now, we should not parallel afterwards
-		// execept user force to do so
+	at := func(i int) T { return elems[i] }
+	return Stream[T]{
+		split: func(n int) []func() (T, bool) {
+			once.Do(doProduce)
+			return splitIndexed(len(elems), at)(n)
+		},
+		config: config{parallel: c.parallel, ordered: c.parallel > 1, pipe: c.pipe, clock: c.clock},
 		nextFn: func() (T, bool) {
-			once.Do(doSort)
+			once.Do(doProduce)
 			index := atomic.AddInt64(&index, 1)
-			// fmt.Println("index:", index, elems)
 			if index > int64(len(elems)) {
 				var zeroVal T
 				return zeroVal, false
@@ -243,39 +570,83 @@ func (s stream[T]) Sorted(comparator Comparator[T]) stream[T] {
 // FlatMap returns a stream consisting of the results of replacing each element of this stream
 // with the contents of a mapped stream produced by applying the provided mapping function to
 // each element. Each mapped stream is closed after its contents have been placed into this
-// stream. (If a mapped stream is null an empty stream is used, instead.)
+// stream, or when this stream is closed if it isn't exhausted by then. (If a mapped
+// stream is the zero Stream an empty stream is used, instead.) For ordered
+// streams, mapper runs in parallel, but the mapped streams are drained lazily, in
+// encounter order, by the goroutine pulling from this stream.
 //
 // Due to the lazy nature of streams, if any of the mapped streams is infinite it will remain
 // unnoticed and some operations (Count, Reduce, Sorted, AllMatch...) will not end.
 //
 // When both the input and output type are the same, the operation can be
 // invoked as the method input.FlatMap(mapper).
-func FlatMap[IN, OUT any](input stream[IN], mapper func(IN) stream[OUT]) stream[OUT] {
-	nextFromInputStream := input.nextFn
-	var nextFromOutputStream func() (OUT, bool)
-	return stream[OUT]{
-		parallel: input.parallel,
-		nextFn: func() (OUT, bool) {
+func FlatMap[IN, OUT any](input Stream[IN], mapper func(IN) Stream[OUT]) Stream[OUT] {
+	if input.ordered {
+		return flatMapSequenced(input, mapper)
+	}
+	// idle holds the mapped streams that are not exhausted yet and that no goroutine
+	// is pulling from. Every pull takes one of them (or maps a new input element), so
+	// for parallel streams each mapped stream is pulled by one goroutine at a time,
+	// and for sequential streams the mapped streams are drained in order. When the
+	// input stream is split, each part gets its own idle list.
+	// A mapped stream is closed once exhausted, and the ones still idle when the
+	// pipeline is closed are closed along with it.
+	return stateless(input, func(next func() (IN, bool)) func() (OUT, bool) {
+		var lock sync.Mutex
+		var idle []Stream[OUT]
+		input.pipe.onClose(func() error {
+			lock.Lock()
+			streams := idle
+			idle = nil
+			lock.Unlock()
+			var errs []error
+			for _, out := range streams {
+				errs = append(errs, out.pipe.close())
+			}
+			return errors.Join(errs...)
+		})
+		takeIdle := func() (Stream[OUT], bool) {
+			lock.Lock()
+			defer lock.Unlock()
+			if len(idle) == 0 {
+				return Stream[OUT]{}, false
+			}
+			out := idle[len(idle)-1]
+			idle = idle[:len(idle)-1]
+			return out, true
+		}
+		putIdle := func(out Stream[OUT]) {
+			lock.Lock()
+			defer lock.Unlock()
+			idle = append(idle, out)
+		}
+		return func() (OUT, bool) {
+			var zeroVal OUT
 			for {
-				if nextFromOutputStream == nil {
-					v, hasNext := nextFromInputStream()
+				outputStream, ok := takeIdle()
+				if !ok {
+					v, hasNext := next()
 					if !hasNext {
-						var zeroVal OUT
 						return zeroVal, false
 					}
-					nextFromOutputStream = mapper(v).nextFn
+					outputStream = mapper(v).orEmpty()
 				}
-				v, hasNext := nextFromOutputStream()
+				v, hasNext := outputStream.nextFn()
 				if hasNext {
+					putIdle(outputStream)
 					return v, true
 				}
-				nextFromOutputStream = nil
+				// forward the errors of the mapped stream to this pipeline
+				outputStream.pipe.close()
+				if err := outputStream.pipe.err(); err != nil && !input.pipe.report(err) {
+					return zeroVal, false
+				}
 			}
-		},
-	}
+		}
+	})
 }
 
-func (s stream[T]) FlatMap(mapper func(T) stream[T]) stream[T] {
+func (s Stream[T]) FlatMap(mapper func(T) Stream[T]) Stream[T] {
 	return FlatMap[T, T](s, mapper)
 }
 
@@ -284,34 +655,44 @@ func (s stream[T]) FlatMap(mapper func(T) stream[T]) stream[T] {
 // This function is equivalent to invoking input.Peek(consumer) as method.
 // For parallel stream pipelines,
 // the action may be called at whatever time and in whatever thread the element is made available by the upstream operation. If the action modifies shared state, it is responsible for providing the required synchronization.
-func Peek[T any](input stream[T], consumer func(T)) stream[T] {
+func Peek[T any](input Stream[T], consumer func(T)) Stream[T] {
 	return input.Peek(consumer)
 }
-func (s stream[T]) Peek(consumer func(T)) stream[T] {
-	return stream[T]{
-		parallel: s.parallel,
-		nextFn: func() (T, bool) {
-			v, hasNext := s.nextFn()
+func (s Stream[T]) Peek(consumer func(T)) Stream[T] {
+	if s.ordered {
+		return mapSequenced(s, func(vals []T) []T {
+			for _, v := range vals {
+				consumer(v)
+			}
+			return vals
+		})
+	}
+	return stateless(s, func(next func() (T, bool)) func() (T, bool) {
+		return func() (T, bool) {
+			v, hasNext := next()
 			if hasNext {
 				consumer(v)
 			}
 			return v, hasNext
-		},
-	}
+		}
+	})
 }
 
 // Skip returns a stream consisting of the remaining elements of this stream after discarding
 // the first n elements of the stream.
+// For parallel streams the discarded elements are the first n pulled by any of the
+// goroutines, which are not necessarily the first n in encounter order.
 // This function is equivalent to invoking input.Skip(n) as method.
-func Skip[T any](input stream[T], n int) stream[T] {
+func Skip[T any](input Stream[T], n int) Stream[T] {
 	return input.Skip(n)
 }
 
-func (s stream[T]) Skip(n int) stream[T] {
-	return stream[T]{
+func (s Stream[T]) Skip(n int) Stream[T] {
+	toSkip := int64(n)
+	return Stream[T]{
+		config: s.config,
 		nextFn: func() (T, bool) {
-			// n is a closure here
-			for ; n > 0; n-- {
+			for atomic.LoadInt64(&toSkip) > 0 && atomic.AddInt64(&toSkip, -1) >= 0 {
 				_, hasNext := s.nextFn()
 				if !hasNext {
 					var zeroVal T
@@ -322,3 +703,134 @@ func (s stream[T]) Skip(n int) stream[T] {
 		},
 	}
 }
+
+// TakeWhile returns a stream consisting of the elements of this stream up to, and
+// excluding, the first one that doesn't match the predicate, so it can end an
+// infinite stream. For parallel streams all the goroutines stop pulling as soon as
+// one of them pulls an element that doesn't match, and the matching elements they
+// pulled before are kept. The goroutines pull the input stream in turn, so these are
+// the leading matching elements, although not in encounter order; with a predicate
+// that matches again after the first element that doesn't match, a few of the
+// following elements may be kept too. For sequential and ordered streams the
+// predicate is invoked in encounter order, and exactly the leading matching
+// elements are kept.
+func (s Stream[T]) TakeWhile(predicate func(T) bool) Stream[T] {
+	return takeWhile(s, predicate, false)
+}
+
+// TakeUntil returns a stream consisting of the elements of this stream up to, and
+// including, the first one that matches the predicate, e.g. a sentinel value. It
+// behaves like TakeWhile for parallel and ordered streams.
+func (s Stream[T]) TakeUntil(predicate func(T) bool) Stream[T] {
+	return takeWhile(s, predicate, true)
+}
+
+// takeWhile returns the stream of the elements of s until the first one for which
+// predicate returns inclusive, which is also kept if inclusive is true.
+func takeWhile[T any](s Stream[T], predicate func(T) bool, inclusive bool) Stream[T] {
+	stopped := int32(0)
+	// split is left off, so the goroutines pull the source in encounter order and the
+	// first element that doesn't match is not the start of a goroutine's own part
+	return Stream[T]{
+		config: s.config,
+		nextFn: func() (T, bool) {
+			var zeroVal T
+			if atomic.LoadInt32(&stopped) == 1 {
+				return zeroVal, false
+			}
+			// an element pulled before the stream is stopped is kept if it matches
+			v, hasNext := s.nextFn()
+			if !hasNext {
+				return zeroVal, false
+			}
+			if predicate(v) != inclusive {
+				return v, true
+			}
+			// only the goroutine stopping the stream keeps the last element
+			if atomic.CompareAndSwapInt32(&stopped, 0, 1) && inclusive {
+				return v, true
+			}
+			return zeroVal, false
+		},
+	}
+}
+
+// DropWhile returns a stream consisting of the elements of this stream from the
+// first one that doesn't match the predicate on. The predicate is no longer invoked
+// once an element doesn't match. For parallel streams, the matching elements pulled
+// by the goroutines before one of them pulls an element that doesn't match are
+// dropped. For sequential and ordered streams, exactly the leading matching elements
+// are dropped.
+func (s Stream[T]) DropWhile(predicate func(T) bool) Stream[T] {
+	dropping := int32(1)
+	return Stream[T]{
+		config: s.config,
+		nextFn: func() (T, bool) {
+			for {
+				// the flag is loaded before pulling, so an element pulled before the
+				// first one that doesn't match is still tested by the predicate
+				if atomic.LoadInt32(&dropping) == 0 {
+					return s.nextFn()
+				}
+				v, hasNext := s.nextFn()
+				if !hasNext {
+					return v, false
+				}
+				if !predicate(v) {
+					atomic.StoreInt32(&dropping, 0)
+					return v, true
+				}
+			}
+		},
+	}
+}
+
+// SkipUntil returns a stream consisting of the elements of this stream from the
+// first one that matches the predicate on, e.g. a start marker or a timestamp
+// cutoff. It is equivalent to DropWhile with the negated predicate.
+func (s Stream[T]) SkipUntil(predicate func(T) bool) Stream[T] {
+	return s.DropWhile(func(v T) bool { return !predicate(v) })
+}
+
+// Scan returns a stream of the successive values of an accumulator: starting from
+// seed, each element of the input stream is combined with the accumulator by fn,
+// and the new accumulator is emitted, e.g. the running totals of the input stream.
+// The accumulator is updated under a lock, so fn is never invoked concurrently. The
+// elements are accumulated in encounter order for sequential and ordered streams,
+// and in no particular order for parallel unordered streams.
+func Scan[I, O any](s Stream[I], seed O, fn func(O, I) O) Stream[O] {
+	return scan(s, seed, fn, false)
+}
+
+// ScanWithSeed is like Scan, but it emits the seed first, so the stream always has
+// one more element than the input stream.
+func ScanWithSeed[I, O any](s Stream[I], seed O, fn func(O, I) O) Stream[O] {
+	return scan(s, seed, fn, true)
+}
+
+func scan[I, O any](s Stream[I], seed O, fn func(O, I) O, emitSeed bool) Stream[O] {
+	var lock sync.Mutex
+	acc := seed
+	return Stream[O]{
+		config: s.config,
+		nextFn: func() (O, bool) {
+			lock.Lock()
+			if emitSeed {
+				emitSeed = false
+				lock.Unlock()
+				return seed, true
+			}
+			lock.Unlock()
+			// the upstream stages run outside the lock, so they stay parallel
+			v, hasNext := s.nextFn()
+			if !hasNext {
+				var zeroVal O
+				return zeroVal, false
+			}
+			lock.Lock()
+			defer lock.Unlock()
+			acc = fn(acc, v)
+			return acc, true
+		},
+	}
+}
@@ -1,8 +1,17 @@
 package stream
 
 import (
+	"context"
+	"errors"
+	"fmt"
 	"reflect"
+	"runtime"
+	"slices"
+	"sort"
+	"sync"
+	"sync/atomic"
 	"testing"
+	"time"
 )
 
 func TestStream_Filter(t *testing.T) {
@@ -11,7 +20,7 @@ func TestStream_Filter(t *testing.T) {
 	}
 	tests := []struct {
 		name string
-		s    stream[int]
+		s    Stream[int]
 		args args
 		want []int
 	}{
@@ -44,7 +53,7 @@ func TestStream_Map(t *testing.T) {
 	}
 	tests := []struct {
 		name string
-		s    stream[int]
+		s    Stream[int]
 		args args
 		want []int
 	}{
@@ -74,7 +83,7 @@ func TestStream_Map(t *testing.T) {
 func TestStream_Sequential(t *testing.T) {
 	tests := []struct {
 		name string
-		s    stream[int]
+		s    Stream[int]
 		want []int
 	}{
 		{
@@ -104,7 +113,7 @@ func TestStream_Take(t *testing.T) {
 	}
 	tests := []struct {
 		name string
-		s    stream[int]
+		s    Stream[int]
 		args args
 		want []int
 	}{
@@ -171,7 +180,7 @@ func TestStream_Sorted(t *testing.T) {
 	cmp := func(a, b int) int { return a - b }
 	tests := []struct {
 		name string
-		s    stream[int]
+		s    Stream[int]
 		want []int
 	}{
 		{
@@ -209,7 +218,7 @@ func TestStream_Sorted(t *testing.T) {
 		s := OfSlice(arr)
 		tests = append(tests, struct {
 			name string
-			s    stream[int]
+			s    Stream[int]
 			want []int
 		}{
 			name: "sorted",
@@ -230,26 +239,54 @@ func TestStream_Sorted(t *testing.T) {
 
 func TestStream_FlatMap(t *testing.T) {
 	type args struct {
-		fn func(int) stream[int]
+		fn func(int) Stream[int]
 	}
 	tests := []struct {
 		name string
-		s    stream[int]
+		s    Stream[int]
 		args args
 		want []int
 	}{
 		{
 			name: "flat map",
 			s:    OfSlice([]int{1, 2, 3}),
-			args: args{fn: func(i int) stream[int] { return OfSlice[int]([]int{i, i}) }},
+			args: args{fn: func(i int) Stream[int] { return OfSlice[int]([]int{i, i}) }},
 			want: []int{1, 1, 2, 2, 3, 3},
 		},
 		{
 			name: "flat map",
 			s:    OfSlice([]int{1, 2, 3}),
-			args: args{fn: func(i int) stream[int] { return OfSlice([]int{i * 2, i * 2}) }},
+			args: args{fn: func(i int) Stream[int] { return OfSlice([]int{i * 2, i * 2}) }},
 			want: []int{2, 2, 4, 4, 6, 6},
 		},
+		{
+			name: "zero mapped stream",
+			s:    OfSlice([]int{1, 2, 3}),
+			args: args{fn: func(i int) Stream[int] {
+				if i == 2 {
+					return Stream[int]{}
+				}
+				return Of(i)
+			}},
+			want: []int{1, 3},
+		},
+		{
+			name: "zero mapped stream ordered",
+			s:    OfSlice([]int{1, 2, 3}).ParallelOrdered(2),
+			args: args{fn: func(i int) Stream[int] {
+				if i == 2 {
+					return Stream[int]{}
+				}
+				return Of(i)
+			}},
+			want: []int{1, 3},
+		},
+		{
+			name: "empty mapped stream",
+			s:    OfSlice([]int{1, 2, 3}),
+			args: args{fn: func(int) Stream[int] { return Empty[int]() }},
+			want: []int{},
+		},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
@@ -265,7 +302,7 @@ func TestStream_Peek(t *testing.T) {
 
 	tests := []struct {
 		name string
-		s    stream[int]
+		s    Stream[int]
 		want []int
 	}{
 		{
@@ -297,7 +334,7 @@ func TestStream_FilterN(t *testing.T) {
 	}
 	tests := []struct {
 		name string
-		s    stream[int]
+		s    Stream[int]
 		args args
 		want []int
 	}{
@@ -330,7 +367,7 @@ func TestStream_Skip(t *testing.T) {
 	}
 	tests := []struct {
 		name string
-		s    stream[int]
+		s    Stream[int]
 		args args
 		want []int
 	}{
@@ -375,3 +412,503 @@ func TestStream_Skip(t *testing.T) {
 	}
 
 }
+
+func TestTransform(t *testing.T) {
+	pairSum := func(s Stream[int]) Stream[int] {
+		return Transform(s, func(next func() (int, bool)) func() (int, bool) {
+			return func() (int, bool) {
+				a, ok := next()
+				if !ok {
+					return 0, false
+				}
+				b, _ := next()
+				return a + b, true
+			}
+		})
+	}
+	tests := []struct {
+		name string
+		s    Stream[int]
+		want []int
+	}{
+		{
+			name: "even length",
+			s:    OfSlice([]int{1, 2, 3, 4}),
+			want: []int{3, 7},
+		},
+		{
+			name: "odd length",
+			s:    OfSlice([]int{1, 2, 3}),
+			want: []int{3, 3},
+		},
+		{
+			name: "empty",
+			s:    OfSlice([]int{}),
+			want: []int{},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := pairSum(tt.s).ToSlice()
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Transform().ToSlice() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+	if p := Transform(OfSlice([]int{1}).Parallel(3), func(next func() (int, bool)) func() (int, bool) {
+		return next
+	}).Parallelism(); p != 3 {
+		t.Errorf("Transform().Parallelism() = %d, want 3", p)
+	}
+}
+
+func TestStream_WithContext(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	got := OfSlice([]int{1, 2, 3, 4, 5, 6}).
+		WithContext(ctx).
+		Peek(func(i int) {
+			if i == 3 {
+				cancel()
+			}
+		}).
+		ToSlice()
+	if want := []int{1, 2, 3}; !reflect.DeepEqual(got, want) {
+		t.Errorf("Stream.WithContext().ToSlice() = %v, want %v", got, want)
+	}
+}
+
+func TestStream_SequentialBuffer(t *testing.T) {
+	for _, bufSize := range []int{0, 1, 16} {
+		// visited is not synchronized: the race detector checks that Peek is invoked sequentially
+		visited := []int{}
+		got := Range(0, 1000).
+			Parallel(8).
+			Map(func(i int) int { return i * 2 }).
+			Sequential(bufSize).
+			Peek(func(i int) { visited = append(visited, i) }).
+			ToSlice()
+		if len(got) != 1000 || len(visited) != 1000 {
+			t.Errorf("bufSize %d: got %d elements and visited %d, want 1000", bufSize, len(got), len(visited))
+		}
+	}
+}
+
+func TestStream_SequentialOrdered(t *testing.T) {
+	var slow inFlight
+	visited := []int{}
+	got := Range(0, 200).
+		ParallelOrdered(4).
+		Map(slowly(&slow, func(i int) int { return i * 2 })).
+		Sequential(8).
+		Peek(func(i int) { visited = append(visited, i) }).
+		ToSlice()
+	want := Range(0, 200).Map(func(i int) int { return i * 2 }).ToSlice()
+	if !reflect.DeepEqual(got, want) || !reflect.DeepEqual(visited, want) {
+		t.Errorf("ToSlice() = %v, visited %v, want %v", got, visited, want)
+	}
+	if slow.maximum() < 2 {
+		t.Errorf("Map() before Sequential() ran in %d goroutine(s), want several", slow.maximum())
+	}
+}
+
+// inFlight measures the maximum number of concurrent invocations of a function.
+type inFlight struct {
+	cur, max int32
+}
+
+func (f *inFlight) enter() {
+	n := atomic.AddInt32(&f.cur, 1)
+	for m := atomic.LoadInt32(&f.max); n > m && !atomic.CompareAndSwapInt32(&f.max, m, n); m = atomic.LoadInt32(&f.max) {
+	}
+}
+
+func (f *inFlight) leave() {
+	atomic.AddInt32(&f.cur, -1)
+}
+
+// maximum returns the maximum number of concurrent invocations so far.
+func (f *inFlight) maximum() int32 {
+	return atomic.LoadInt32(&f.max)
+}
+
+// slowly returns fn taking a millisecond more, with its invocations measured by f.
+func slowly[I, O any](f *inFlight, fn func(I) O) func(I) O {
+	return func(v I) O {
+		f.enter()
+		defer f.leave()
+		time.Sleep(time.Millisecond)
+		return fn(v)
+	}
+}
+
+func TestStream_SequentialEarlyStop(t *testing.T) {
+	before := runtime.NumGoroutine()
+	got := Generate(func() (int, bool) { return 1, true }).
+		Parallel(8).
+		Sequential(4).
+		Limit(10).
+		ToSlice()
+	if len(got) != 10 {
+		t.Errorf("Stream.Sequential().Limit(10).ToSlice() has %d elements, want 10", len(got))
+	}
+	checkGoroutines(t, before)
+}
+
+func TestStream_SequentialPanic(t *testing.T) {
+	defer func() {
+		if _, ok := recover().(*PanicError); !ok {
+			t.Errorf("want a *PanicError")
+		}
+	}()
+	Range(0, 1000).Parallel(4).Map(func(i int) int {
+		if i == 500 {
+			panic("boom")
+		}
+		return i
+	}).Sequential(1).ToSlice()
+}
+
+func TestStream_OnClose(t *testing.T) {
+	var closed []string
+	handler := func(name string) func() error {
+		return func() error {
+			closed = append(closed, name)
+			return nil
+		}
+	}
+	tests := []struct {
+		name     string
+		terminal func(s Stream[int])
+	}{
+		{"ToSlice", func(s Stream[int]) { s.ToSlice() }},
+		{"AnyMatch", func(s Stream[int]) { s.AnyMatch(func(n int) bool { return n == 2 }) }},
+		{"Limit", func(s Stream[int]) { s.Limit(1).ForEach(func(int) {}) }},
+		{"parallel", func(s Stream[int]) { s.Parallel(4).Count() }},
+		{"ordered", func(s Stream[int]) { s.ParallelOrdered(4).FindFirst() }},
+		{"panic", func(s Stream[int]) {
+			defer func() { recover() }()
+			s.ForEach(func(int) { panic("boom") })
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			closed = nil
+			s := Range(0, 10).OnClose(handler("source"))
+			s = Map(s, func(n int) int { return n * 2 }).Filter(func(n int) bool { return n > 0 }).OnClose(handler("filter"))
+			s = Concat(s, Of(1, 2).OnClose(handler("second")))
+			tt.terminal(s)
+			if want := []string{"filter", "source", "second"}; !reflect.DeepEqual(closed, want) {
+				t.Errorf("closed = %v, want %v", closed, want)
+			}
+			s.Close()
+			if len(closed) != 3 {
+				t.Errorf("handlers invoked again on Close: %v", closed)
+			}
+		})
+	}
+
+	t.Run("FlatMap", func(t *testing.T) {
+		closed = nil
+		s := FlatMap(Range(0, 3), func(n int) Stream[int] {
+			return Range(0, 3).OnClose(handler(string(rune('a' + n))))
+		})
+		if got := s.Limit(4).ToSlice(); !reflect.DeepEqual(got, []int{0, 1, 2, 0}) {
+			t.Errorf("ToSlice() = %v", got)
+		}
+		// the first mapped stream is exhausted, the second one is closed with the stream
+		if want := []string{"a", "b"}; !reflect.DeepEqual(closed, want) {
+			t.Errorf("closed = %v, want %v", closed, want)
+		}
+	})
+
+	t.Run("errors", func(t *testing.T) {
+		errClose := errors.New("close failed")
+		_, err := Of(1, 2, 3).OnClose(func() error { return errClose }).ToSliceErr()
+		if !errors.Is(err, errClose) {
+			t.Errorf("ToSliceErr() error = %v, want %v", err, errClose)
+		}
+		s := Of(1, 2, 3).OnClose(func() error { return errClose })
+		if err := s.Close(); !errors.Is(err, errClose) {
+			t.Errorf("Close() = %v, want %v", err, errClose)
+		}
+	})
+}
+
+func TestStream_TakeWhile(t *testing.T) {
+	below := func(n int) func(int) bool { return func(v int) bool { return v < n } }
+	counter := func() Stream[int] {
+		i := 0
+		return Generate(func() (int, bool) { i++; return i, true })
+	}
+	tests := []struct {
+		name string
+		s    Stream[int]
+		want []int
+	}{
+		{"TakeWhile", Of(1, 2, 5, 1).TakeWhile(below(3)), []int{1, 2}},
+		{"TakeWhile infinite", counter().TakeWhile(below(4)), []int{1, 2, 3}},
+		{"TakeWhile none", Of(5, 1).TakeWhile(below(3)), []int{}},
+		{"TakeUntil", Of(1, 2, 5, 1).TakeUntil(func(v int) bool { return v == 5 }), []int{1, 2, 5}},
+		{"TakeUntil no match", Of(1, 2).TakeUntil(func(v int) bool { return v == 5 }), []int{1, 2}},
+		{"DropWhile", Of(1, 2, 5, 1).DropWhile(below(3)), []int{5, 1}},
+		{"DropWhile all", Of(1, 2).DropWhile(below(3)), []int{}},
+		{"SkipUntil", Of(1, 2, 5, 1).SkipUntil(func(v int) bool { return v == 5 }), []int{5, 1}},
+		{
+			"ordered TakeWhile",
+			Map(Range(0, 10000).ParallelOrdered(8), func(n int) int { return n * 2 }).TakeWhile(below(100)),
+			Range(0, 50).Map(func(n int) int { return n * 2 }).ToSlice(),
+		},
+		{
+			"ordered DropWhile",
+			Map(Range(0, 100).ParallelOrdered(8), func(n int) int { return n * 2 }).DropWhile(below(100)).Limit(3),
+			[]int{100, 102, 104},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.s.ToSlice(); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ToSlice() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+
+	t.Run("parallel stops all the goroutines", func(t *testing.T) {
+		for _, p := range []int{1, 8} {
+			got := Range(0, 1<<20).Parallel(p).TakeWhile(below(1000)).ToSlice()
+			for _, v := range got {
+				if v >= 1000 {
+					t.Fatalf("parallel %d: got %d", p, v)
+				}
+			}
+			// the source is pulled in order, so all the elements before 1000 are kept
+			if sort.Ints(got); !reflect.DeepEqual(got, Range(0, 1000).ToSlice()) {
+				t.Errorf("parallel %d: got %d elements, want 1000", p, len(got))
+			}
+			if n := len(Range(0, 1<<20).Parallel(p).TakeUntil(func(v int) bool { return v%1000 == 999 }).ToSlice()); n == 0 || n > 1000*p {
+				t.Errorf("parallel %d: TakeUntil got %d elements", p, n)
+			}
+			dropped := Range(0, 1<<20).Parallel(p).DropWhile(below(1000)).ToSlice()
+			for _, v := range dropped {
+				if v < 1000 {
+					t.Fatalf("parallel %d: DropWhile got %d", p, v)
+				}
+			}
+			if len(dropped) != 1<<20-1000 {
+				t.Errorf("parallel %d: DropWhile got %d elements", p, len(dropped))
+			}
+		}
+	})
+}
+
+func TestScan(t *testing.T) {
+	sum := func(acc, n int) int { return acc + n }
+	tests := []struct {
+		name string
+		s    Stream[int]
+		want []int
+	}{
+		{"running total", Scan(Of(1, 2, 3, 4), 0, sum), []int{1, 3, 6, 10}},
+		{"running max", Scan(Of(3, 1, 4, 1, 5), 0, func(acc, n int) int { return max(acc, n) }), []int{3, 3, 4, 4, 5}},
+		{"empty", Scan(Of[int](), 0, sum), []int{}},
+		{"with seed", ScanWithSeed(Of(1, 2, 3), 10, sum), []int{10, 11, 13, 16}},
+		{"with seed empty", ScanWithSeed(Of[int](), 10, sum), []int{10}},
+		{"ordered", Scan(Map(Range(1, 101).ParallelOrdered(4), func(n int) int { return n }), 0, sum).Skip(99), []int{5050}},
+		{"ordered with seed", ScanWithSeed(Range(0, 100).ParallelOrdered(4), 0, sum).Limit(4), []int{0, 0, 1, 3}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.s.ToSlice(); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ToSlice() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+
+	lengths := Scan(Of("a", "bb", "ccc"), "", func(acc string, s string) string { return acc + s }).ToSlice()
+	if want := []string{"a", "abb", "abbccc"}; !reflect.DeepEqual(lengths, want) {
+		t.Errorf("Scan() = %v, want %v", lengths, want)
+	}
+
+	var slow inFlight
+	totals := Scan(Map(Range(1, 101).Parallel(4), slowly(&slow, func(n int) int { return n })), 0, sum).ToSlice()
+	if len(totals) != 100 || slices.Max(totals) != 5050 {
+		t.Errorf("parallel Scan() = %v, want 100 totals up to 5050", totals)
+	}
+	if slow.maximum() < 2 {
+		t.Errorf("Map() before Scan() ran in %d goroutine(s), want several", slow.maximum())
+	}
+}
+
+func TestDistinct(t *testing.T) {
+	type user struct {
+		id   int
+		tags []string // not comparable
+	}
+	users := Of(user{1, nil}, user{2, []string{"a"}}, user{1, []string{"b"}}, user{3, nil})
+	gotUsers := Map(DistinctBy(users, func(u user) int { return u.id }), func(u user) int { return len(u.tags) }).ToSlice()
+	if want := []int{0, 1, 0}; !reflect.DeepEqual(gotUsers, want) {
+		t.Errorf("DistinctBy() = %v, want %v", gotUsers, want)
+	}
+
+	tests := []struct {
+		name string
+		s    Stream[int]
+		want []int
+	}{
+		{"Distinct", Distinct(Of(1, 2, 1, 3, 2)), []int{1, 2, 3}},
+		{"DistinctUntilChanged", DistinctUntilChanged(Of(1, 1, 2, 2, 2, 1, 3, 3)), []int{1, 2, 1, 3}},
+		{"DistinctUntilChangedFunc", DistinctUntilChangedFunc(Of(1, 3, 2, 4, 5), func(a, b int) bool { return a%2 == b%2 }), []int{1, 2, 5}},
+		// 1 is forgotten when 3 is added, 2 is still remembered
+		{"DistinctLRU", DistinctLRU(Of(1, 2, 1, 2, 3, 1, 2), 2), []int{1, 2, 3, 1, 2}},
+		{"DistinctLRU infinite", DistinctLRU(Generate(func() (int, bool) { return 7, true }), 1).Limit(1), []int{7}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.s.ToSlice(); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ToSlice() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+
+	t.Run("parallel", func(t *testing.T) {
+		mod := func(n int) int { return n % 100 }
+		if got := DistinctBy(Range(0, 10000).Parallel(8), mod).Count(); got != 100 {
+			t.Errorf("DistinctBy() count = %d, want 100", got)
+		}
+		if got := DistinctLRU(Map(Range(0, 10000).Parallel(8), mod), 200).Count(); got != 100 {
+			t.Errorf("DistinctLRU() count = %d, want 100", got)
+		}
+		if got := DistinctUntilChanged(Map(Range(0, 10000).ParallelOrdered(8), func(n int) int { return n / 10 })).Count(); got != 1000 {
+			t.Errorf("DistinctUntilChanged() count = %d, want 1000", got)
+		}
+	})
+}
+
+func TestSortedBy(t *testing.T) {
+	type item struct{ key, pos int }
+	items := make([]item, 10000)
+	for i := range items {
+		items[i] = item{key: (i * 7919) % 10, pos: i}
+	}
+	key := func(it item) int { return it.key }
+
+	got := SortedBy(OfSlice(items), key).ToSlice()
+	for i := 1; i < len(got); i++ {
+		if got[i-1].key > got[i].key {
+			t.Fatalf("SortedBy() not sorted at %d: %v %v", i, got[i-1], got[i])
+		}
+	}
+	for _, p := range []int{1, 4} {
+		got := OfSlice(items).ParallelOrdered(p).SortedStable(Comparing(key)).ToSlice()
+		for i := 1; i < len(got); i++ {
+			prev, cur := got[i-1], got[i]
+			if prev.key > cur.key || prev.key == cur.key && prev.pos > cur.pos {
+				t.Fatalf("parallel %d: SortedStable() not stable at %d: %v %v", p, i, prev, cur)
+			}
+		}
+	}
+}
+
+func TestParallelMergeSort(t *testing.T) {
+	type item struct{ key, pos int }
+	byKey := Comparing(func(it item) int { return it.key })
+	for _, n := range []int{0, 1, ParallelMergeSortThreshold + 1, 50000} {
+		for _, p := range []int{1, 2, 3, 8} {
+			keys := randomSlice(n)
+			items := make([]item, n)
+			for i, k := range keys {
+				items[i] = item{key: k % 100, pos: i}
+			}
+			parallelMergeSort(items, byKey, p, true)
+			for i := 1; i < n; i++ {
+				prev, cur := items[i-1], items[i]
+				if prev.key > cur.key || prev.key == cur.key && prev.pos > cur.pos {
+					t.Fatalf("n %d, parallel %d: not stable at %d: %v %v", n, p, i, prev, cur)
+				}
+			}
+
+			want := append([]int{}, keys...)
+			SortSlice(want, Natural[int])
+			parallelMergeSort(keys, Natural[int], p, false)
+			if !reflect.DeepEqual(keys, want) {
+				t.Fatalf("n %d, parallel %d: parallelMergeSort() not sorted", n, p)
+			}
+		}
+	}
+}
+
+func TestStream_SortedParallelism(t *testing.T) {
+	var running, maxRunning int32
+	var lock sync.Mutex
+	got := OfSlice(randomSlice(1000)).Parallel(4).Sorted(Natural[int]).
+		Map(func(n int) int {
+			lock.Lock()
+			running++
+			maxRunning = max(maxRunning, running)
+			lock.Unlock()
+			time.Sleep(time.Millisecond)
+			lock.Lock()
+			running--
+			lock.Unlock()
+			return n
+		}).ToSlice()
+	for i := 1; i < len(got); i++ {
+		if got[i-1] > got[i] {
+			t.Fatalf("Sorted().Map() not sorted at %d: %v %v", i, got[i-1], got[i])
+		}
+	}
+	if maxRunning < 2 {
+		t.Errorf("Sorted().Map() ran in %d goroutine(s), want several", maxRunning)
+	}
+}
+
+func TestStream_SortedFlatMapLazy(t *testing.T) {
+	naturals := func(int) Stream[int] {
+		n := 0
+		return Generate(func() (int, bool) { n++; return n, true })
+	}
+	for _, p := range []int{1, 4} {
+		s := Of(3, 1, 2).Parallel(p).Sorted(Natural[int])
+		if s.ordered != (p > 1) {
+			t.Errorf("parallel %d: Sorted() ordered = %v", p, s.ordered)
+		}
+		got := s.FlatMap(naturals).Limit(3).ToSlice()
+		if want := []int{1, 2, 3}; !reflect.DeepEqual(got, want) {
+			t.Errorf("parallel %d: Sorted().FlatMap(infinite).Limit(3) = %v, want %v", p, got, want)
+		}
+	}
+}
+
+func benchmarkSort(b *testing.B, sort func([]int)) {
+	input := randomSlice(1_000_000)
+	elems := make([]int, len(input))
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		b.StopTimer()
+		copy(elems, input)
+		b.StartTimer()
+		sort(elems)
+	}
+}
+
+func BenchmarkSortSlice(b *testing.B) {
+	benchmarkSort(b, func(elems []int) { SortSlice(elems, Natural[int]) })
+}
+
+func BenchmarkParallelMergeSort(b *testing.B) {
+	for _, p := range []int{2, 4, 8} {
+		b.Run(fmt.Sprintf("parallel %d", p), func(b *testing.B) {
+			benchmarkSort(b, func(elems []int) { parallelMergeSort(elems, Natural[int], p, false) })
+		})
+	}
+}
+
+func BenchmarkStream_Sorted(b *testing.B) {
+	input := randomSlice(1_000_000)
+	for _, p := range []int{1, 4} {
+		b.Run(fmt.Sprintf("parallel %d", p), func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				OfSlice(input).Parallel(p).Sorted(Natural[int]).Count()
+			}
+		})
+	}
+}
@@ -0,0 +1,138 @@
+//go:build go1.23
+
+package stream
+
+import (
+	"iter"
+	"sync"
+)
+
+// FromSeq returns a stream of the values yielded by seq, so streams can be fed from
+// maps.Keys, slices.Values or any other range-over-func iterator.
+// The iterator is not started until the first element is pulled, and it is resumed
+// under a lock so the stream can be safely consumed in parallel. Closing the stream
+// before the iterator is exhausted stops it, so its deferred calls run.
+func FromSeq[T any](seq iter.Seq[T]) Stream[T] {
+	var (
+		lock sync.Mutex
+		next func() (T, bool)
+		stop func()
+		done bool
+	)
+	c := newConfig()
+	c.pipe.onClose(func() error {
+		lock.Lock()
+		defer lock.Unlock()
+		if next != nil && !done {
+			done = true
+			stop()
+		}
+		return nil
+	})
+	return Stream[T]{
+		config: c,
+		nextFn: func() (T, bool) {
+			lock.Lock()
+			defer lock.Unlock()
+			var zeroVal T
+			if done {
+				return zeroVal, false
+			}
+			if next == nil {
+				next, stop = iter.Pull(seq)
+			}
+			v, ok := next()
+			if !ok {
+				done = true
+				stop()
+				return zeroVal, false
+			}
+			return v, true
+		},
+	}
+}
+
+// FromSeq2 returns a stream of the key/value pairs yielded by seq, such as the
+// entries produced by maps.All or slices.All.
+func FromSeq2[K, V any](seq iter.Seq2[K, V]) Stream[Pair[K, V]] {
+	return FromSeq(func(yield func(Pair[K, V]) bool) {
+		for k, v := range seq {
+			if !yield(PairOf(k, v)) {
+				return
+			}
+		}
+	})
+}
+
+// All returns an iterator over the elements of the stream, so it can be consumed
+// with a for-range loop. The loop body always runs on the calling goroutine. For
+// parallel streams the elements are pulled by Parallelism() goroutines and arrive
+// in no particular order, unless the stream is ordered.
+// Breaking out of the loop stops pulling from the stream, and for parallel streams
+// it waits for the pulling goroutines to finish their current element.
+func (s Stream[T]) All() iter.Seq[T] {
+	return func(yield func(T) bool) {
+		defer s.pipe.close()
+		if !s.concurrent() {
+			for v, ok := s.nextFn(); ok; v, ok = s.nextFn() {
+				if !yield(v) {
+					return
+				}
+			}
+			return
+		}
+		done := make(chan struct{})
+		resCh := make(chan T, s.parallel)
+		var perr *PanicError
+		go func() {
+			perr = s.runParallel(func(next func() (T, bool)) {
+				for {
+					select {
+					case <-done:
+						return
+					default:
+					}
+					v, hasNext := next()
+					if !hasNext {
+						return
+					}
+					select {
+					case resCh <- v:
+					case <-done:
+						return
+					}
+				}
+			})
+			close(resCh)
+		}()
+		defer func() {
+			close(done)
+			// wait for the workers to finish their current element
+			for range resCh {
+			}
+		}()
+		for v := range resCh {
+			if !yield(v) {
+				return
+			}
+		}
+		if perr != nil {
+			panic(perr)
+		}
+	}
+}
+
+// Enumerate returns an iterator over the elements of the stream paired with their
+// position in the iteration, starting at 0. Positions are assigned as elements are
+// delivered to the loop body, so they are always consecutive even for parallel streams.
+func (s Stream[T]) Enumerate() iter.Seq2[int, T] {
+	return func(yield func(int, T) bool) {
+		i := 0
+		for v := range s.All() {
+			if !yield(i, v) {
+				return
+			}
+			i++
+		}
+	}
+}
@@ -0,0 +1,140 @@
+//go:build go1.23
+
+package stream
+
+import (
+	"maps"
+	"reflect"
+	"slices"
+	"sync/atomic"
+	"testing"
+)
+
+func TestFromSeq(t *testing.T) {
+	tests := []struct {
+		name string
+		s    Stream[int]
+		want []int
+	}{
+		{
+			name: "from slice values",
+			s:    FromSeq(slices.Values([]int{1, 2, 3})),
+			want: []int{1, 2, 3},
+		},
+		{
+			name: "from sorted map keys",
+			s:    FromSeq(slices.Values(slices.Sorted(maps.Keys(map[int]string{3: "c", 1: "a", 2: "b"})))),
+			want: []int{1, 2, 3},
+		},
+		{
+			name: "from empty sequence",
+			s:    FromSeq(slices.Values([]int{})),
+			want: []int{},
+		},
+		{
+			name: "parallel",
+			s:    FromSeq(slices.Values([]int{3, 1, 2})).Parallel(4).Sorted(Natural[int]),
+			want: []int{1, 2, 3},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := tt.s.ToSlice()
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("FromSeq().ToSlice() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestFromSeq_Close(t *testing.T) {
+	stopped := false
+	seq := func(yield func(int) bool) {
+		defer func() { stopped = true }()
+		for i := 0; ; i++ {
+			if !yield(i) {
+				return
+			}
+		}
+	}
+	if got := FromSeq(seq).Limit(2).ToSlice(); !reflect.DeepEqual(got, []int{0, 1}) {
+		t.Errorf("ToSlice() = %v", got)
+	}
+	if !stopped {
+		t.Errorf("iterator not stopped when the stream was closed")
+	}
+}
+
+func TestFromSeq2(t *testing.T) {
+	got := FromSeq2(slices.All([]string{"a", "b"})).ToSlice()
+	want := []Pair[int, string]{{0, "a"}, {1, "b"}}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("FromSeq2().ToSlice() = %v, want %v", got, want)
+	}
+}
+
+func TestStream_All(t *testing.T) {
+	tests := []struct {
+		name string
+		s    Stream[int]
+		want []int
+	}{
+		{
+			name: "sequential",
+			s:    Of(1, 2, 3),
+			want: []int{1, 2, 3},
+		},
+		{
+			name: "parallel",
+			s:    Of(3, 1, 2).Parallel(3),
+			want: []int{1, 2, 3},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := []int{}
+			for v := range tt.s.All() {
+				got = append(got, v)
+			}
+			slices.Sort(got)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Stream.All() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestStream_All_Break(t *testing.T) {
+	for _, p := range []int{1, 8} {
+		pulled := int64(0)
+		s := Generate(func() (int, bool) {
+			return int(atomic.AddInt64(&pulled, 1)), true
+		}).Parallel(p)
+		n := 0
+		for range s.All() {
+			n++
+			if n == 10 {
+				break
+			}
+		}
+		after := atomic.LoadInt64(&pulled)
+		if after < 10 {
+			t.Errorf("parallel %d: pulled %d elements, want at least 10", p, after)
+		}
+		if again := atomic.LoadInt64(&pulled); again != after {
+			t.Errorf("parallel %d: generation continued after break: %d != %d", p, again, after)
+		}
+	}
+}
+
+func TestStream_Enumerate(t *testing.T) {
+	var idx []int
+	var vals []string
+	for i, v := range Of("a", "b", "c").Enumerate() {
+		idx = append(idx, i)
+		vals = append(vals, v)
+	}
+	if !reflect.DeepEqual(idx, []int{0, 1, 2}) || !reflect.DeepEqual(vals, []string{"a", "b", "c"}) {
+		t.Errorf("Stream.Enumerate() = %v %v", idx, vals)
+	}
+}
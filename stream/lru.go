@@ -0,0 +1,35 @@
+package stream
+
+import "container/list"
+
+// lruSet is a set that holds at most capacity elements, forgetting the least
+// recently touched one when it is full. It is not safe for concurrent use.
+type lruSet[T comparable] struct {
+	capacity int
+	order    *list.List // most recently touched first
+	elems    map[T]*list.Element
+}
+
+func newLRUSet[T comparable](capacity int) *lruSet[T] {
+	return &lruSet[T]{
+		capacity: capacity,
+		order:    list.New(),
+		elems:    make(map[T]*list.Element, capacity),
+	}
+}
+
+// touch makes v the most recently touched element of the set, adding it if needed,
+// and returns whether it was already in the set.
+func (s *lruSet[T]) touch(v T) bool {
+	if e, ok := s.elems[v]; ok {
+		s.order.MoveToFront(e)
+		return true
+	}
+	if s.order.Len() >= s.capacity {
+		oldest := s.order.Back()
+		s.order.Remove(oldest)
+		delete(s.elems, oldest.Value.(T))
+	}
+	s.elems[v] = s.order.PushFront(v)
+	return false
+}
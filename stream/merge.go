@@ -0,0 +1,191 @@
+package stream
+
+import (
+	"container/heap"
+	"sync"
+)
+
+// Merge returns a stream of the elements of all the input streams, pulled
+// concurrently: every input is pulled by its own goroutine, and the elements are
+// emitted as soon as they are ready, whichever the input. The elements of each input
+// keep their relative order, but the inputs are mixed in no particular order.
+// Unlike Concat, a slow input (e.g. an OfChannel source) doesn't hold back the others.
+// The goroutines are started on the first pull and stopped when the stream is closed,
+// which closes all the inputs. A panic in one of them is re-raised on the goroutine
+// pulling from the stream once the others are stopped.
+func Merge[T any](streams ...Stream[T]) Stream[T] {
+	c := mergeConfig(streams)
+	c.ordered = false
+	var once, stopOnce sync.Once
+	var resCh chan T
+	var perr *PanicError
+	done := make(chan struct{})
+	stop := func() { stopOnce.Do(func() { close(done) }) }
+	start := func() {
+		resCh = make(chan T)
+		c.pipe.onClose(func() error { stop(); return nil })
+		var wg sync.WaitGroup
+		var perrOnce sync.Once
+		for _, s := range streams {
+			s := s
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				defer func() {
+					if r := recover(); r != nil {
+						perrOnce.Do(func() { perr = asPanicError(r) })
+						stop()
+					}
+				}()
+				for {
+					v, hasNext := s.nextFn()
+					if !hasNext {
+						return
+					}
+					select {
+					case resCh <- v:
+					case <-done:
+						return
+					}
+				}
+			}()
+		}
+		go func() {
+			wg.Wait()
+			close(resCh)
+		}()
+	}
+	return Stream[T]{
+		config: c,
+		nextFn: func() (T, bool) {
+			once.Do(start)
+			v, ok := <-resCh
+			if !ok && perr != nil {
+				panic(perr)
+			}
+			return v, ok
+		},
+	}
+}
+
+// Interleave returns a stream of the elements of the input streams taken in turns,
+// one from each input: the first element of each input, then the second of each
+// input and so on. The inputs that end are skipped, so the stream ends once all of
+// them have ended. Inputs are pulled one at a time, under a lock, so the order is
+// deterministic as long as the inputs are sequential or ordered, and the stream is
+// then ordered, so the following stages keep it.
+func Interleave[T any](streams ...Stream[T]) Stream[T] {
+	var lock sync.Mutex
+	active := append([]Stream[T]{}, streams...)
+	cur := 0
+	return Stream[T]{
+		config: mergeConfig(streams),
+		nextFn: func() (T, bool) {
+			lock.Lock()
+			defer lock.Unlock()
+			for len(active) > 0 {
+				cur %= len(active)
+				v, hasNext := active[cur].nextFn()
+				if hasNext {
+					cur++
+					return v, true
+				}
+				active = append(active[:cur], active[cur+1:]...)
+			}
+			var zeroVal T
+			return zeroVal, false
+		},
+	}
+}
+
+// MergeSorted returns a stream of the elements of the input streams, which must
+// already be sorted according to cmp, in the order defined by cmp. It keeps the head
+// of every input in a heap, so the inputs are merged lazily, with one pull per
+// element. Equal elements are emitted in the order of their inputs. The stream is
+// ordered, so the following stages keep the elements sorted even when it is parallel.
+func MergeSorted[T any](cmp Comparator[T], streams ...Stream[T]) Stream[T] {
+	var lock sync.Mutex
+	var h *mergeHeap[T]
+	// the output is ordered whatever the inputs, so the following stages keep it sorted
+	c := mergeConfig(streams)
+	c.ordered = true
+	return Stream[T]{
+		config: c,
+		nextFn: func() (T, bool) {
+			lock.Lock()
+			defer lock.Unlock()
+			if h == nil {
+				h = &mergeHeap[T]{cmp: cmp}
+				for i, s := range streams {
+					if v, hasNext := s.nextFn(); hasNext {
+						h.heads = append(h.heads, mergeHead[T]{v: v, input: i})
+					}
+				}
+				heap.Init(h)
+			}
+			if h.Len() == 0 {
+				var zeroVal T
+				return zeroVal, false
+			}
+			head := h.heads[0]
+			if v, hasNext := streams[head.input].nextFn(); hasNext {
+				h.heads[0].v = v
+				heap.Fix(h, 0)
+			} else {
+				heap.Pop(h)
+			}
+			return head.v, true
+		},
+	}
+}
+
+// mergeHead is the next element of an input of MergeSorted.
+type mergeHead[T any] struct {
+	v     T
+	input int
+}
+
+// mergeHeap is the heap of the next elements of the inputs of MergeSorted.
+type mergeHeap[T any] struct {
+	heads []mergeHead[T]
+	cmp   Comparator[T]
+}
+
+func (h *mergeHeap[T]) Len() int { return len(h.heads) }
+
+func (h *mergeHeap[T]) Less(i, j int) bool {
+	if c := h.cmp(h.heads[i].v, h.heads[j].v); c != 0 {
+		return c < 0
+	}
+	return h.heads[i].input < h.heads[j].input
+}
+
+func (h *mergeHeap[T]) Swap(i, j int) { h.heads[i], h.heads[j] = h.heads[j], h.heads[i] }
+
+func (h *mergeHeap[T]) Push(x any) { h.heads = append(h.heads, x.(mergeHead[T])) }
+
+func (h *mergeHeap[T]) Pop() any {
+	last := h.heads[len(h.heads)-1]
+	h.heads = h.heads[:len(h.heads)-1]
+	return last
+}
+
+// mergeConfig returns the config of a stream combining the elements of several
+// streams, which is closed along with all of them. It is ordered when all the
+// streams are sequential or ordered, and as parallel as the most parallel of them.
+func mergeConfig[T any](streams []Stream[T]) config {
+	c := newConfig()
+	pipes := make([]*pipeline, len(streams))
+	c.ordered = len(streams) > 0
+	for i, s := range streams {
+		pipes[i] = s.pipe
+		// a sequential input has a deterministic order too
+		c.ordered = c.ordered && (s.ordered || s.parallel <= 1)
+		c.parallel = max(c.parallel, s.parallel)
+	}
+	c.pipe = joinPipelines(pipes...)
+	if len(streams) > 0 {
+		c.clock = streams[0].clock
+	}
+	return c
+}
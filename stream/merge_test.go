@@ -0,0 +1,89 @@
+package stream
+
+import (
+	"reflect"
+	"runtime"
+	"sort"
+	"testing"
+)
+
+func TestMerge(t *testing.T) {
+	before := runtime.NumGoroutine()
+	slow := make(chan int)
+	fast := Range(0, 100)
+	// the fast input is drained while the slow one is still waiting for a value
+	merged := Merge(OfChannel(slow), fast)
+	got := merged.Limit(100).ToSlice()
+	if want := Range(0, 100).ToSlice(); !reflect.DeepEqual(got, want) {
+		t.Errorf("Merge() = %v, want %v", got, want)
+	}
+	close(slow)
+	checkGoroutines(t, before)
+
+	got = Merge(Range(0, 500), Range(500, 1000).Parallel(4), Of[int]()).Parallel(4).ToSlice()
+	sort.Ints(got)
+	if want := Range(0, 1000).ToSlice(); !reflect.DeepEqual(got, want) {
+		t.Errorf("Merge() = %d elements, want %d", len(got), len(want))
+	}
+	if got := Merge[int]().ToSlice(); len(got) != 0 {
+		t.Errorf("Merge() of no stream = %v", got)
+	}
+}
+
+func TestMerge_Panic(t *testing.T) {
+	before := runtime.NumGoroutine()
+	defer func() {
+		if perr, ok := recover().(*PanicError); !ok || perr.Value != "boom" {
+			t.Errorf("recovered %v, want a *PanicError of boom", perr)
+		}
+		checkGoroutines(t, before)
+	}()
+	boom := Generate(func() (int, bool) { panic("boom") })
+	Merge(Generate(func() (int, bool) { return 1, true }), boom).ForEach(func(int) {})
+	t.Errorf("Merge() didn't panic")
+}
+
+func TestInterleave(t *testing.T) {
+	got := Interleave(Of(1, 2, 3), Of(10), Of[int](), Of(100, 200)).ToSlice()
+	if want := []int{1, 10, 100, 2, 200, 3}; !reflect.DeepEqual(got, want) {
+		t.Errorf("Interleave() = %v, want %v", got, want)
+	}
+
+	var slow inFlight
+	sorted := Range(0, 50).Map(func(n int) int { return 49 - n }).Parallel(4).Sorted(Natural[int])
+	got = Map(Interleave(sorted, Range(100, 150)), slowly(&slow, func(n int) int { return n })).ToSlice()
+	want := make([]int, 0, 100)
+	for i := 0; i < 50; i++ {
+		want = append(want, i, 100+i)
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Interleave() = %v, want %v", got, want)
+	}
+}
+
+func TestMergeSorted(t *testing.T) {
+	type item struct{ key, input int }
+	byKey := func(a, b item) int { return a.key - b.key }
+	got := MergeSorted(byKey,
+		Of(item{1, 0}, item{4, 0}, item{7, 0}),
+		Of(item{2, 1}, item{4, 1}),
+		Of[item](),
+		Of(item{0, 3}, item{4, 3}, item{9, 3}),
+	).ToSlice()
+	want := []item{{0, 3}, {1, 0}, {2, 1}, {4, 0}, {4, 1}, {4, 3}, {7, 0}, {9, 3}}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("MergeSorted() = %v, want %v", got, want)
+	}
+	if got := MergeSorted(Natural[int], Range(0, 3)).Limit(2).ToSlice(); !reflect.DeepEqual(got, []int{0, 1}) {
+		t.Errorf("MergeSorted() = %v", got)
+	}
+
+	t.Run("parallel sorted input with a slow stage", func(t *testing.T) {
+		var slow inFlight
+		sorted := Range(0, 200).Map(func(n int) int { return 199 - n }).Parallel(4).Sorted(Natural[int])
+		got := Map(MergeSorted(Natural[int], sorted, Range(100, 300)), slowly(&slow, func(n int) int { return n })).ToSlice()
+		if len(got) != 400 || !sort.IntsAreSorted(got) {
+			t.Errorf("MergeSorted() = %v, want 400 sorted elements", got)
+		}
+	})
+}
@@ -44,11 +44,77 @@ func Inverse[T any](cmp Comparator[T]) Comparator[T] {
 	}
 }
 
+// Comparing returns a Comparator ordering the elements by the natural order of the
+// keys returned by keyFn, e.g. Comparing(func(p Person) string { return p.Name }).
+func Comparing[T any, K constraints.Ordered](keyFn func(T) K) Comparator[T] {
+	return ComparingWith(keyFn, Natural[K])
+}
+
+// ComparingWith returns a Comparator ordering the elements by the keys returned by
+// keyFn, compared with cmp.
+func ComparingWith[T, K any](keyFn func(T) K, cmp Comparator[K]) Comparator[T] {
+	return func(a, b T) int {
+		return cmp(keyFn(a), keyFn(b))
+	}
+}
+
+// ThenComparing returns a Comparator that orders the elements with cmp, and the ones
+// that are equal according to cmp with next, e.g. to sort by a second field.
+func (cmp Comparator[T]) ThenComparing(next Comparator[T]) Comparator[T] {
+	return func(a, b T) int {
+		if c := cmp(a, b); c != 0 {
+			return c
+		}
+		return next(a, b)
+	}
+}
+
+// ThenComparingBy returns a Comparator that orders the elements with cmp, and the
+// ones that are equal according to cmp by the natural order of the keys returned by
+// keyFn. It is equivalent to cmp.ThenComparing(Comparing(keyFn)).
+func ThenComparingBy[T any, K constraints.Ordered](cmp Comparator[T], keyFn func(T) K) Comparator[T] {
+	return cmp.ThenComparing(Comparing(keyFn))
+}
+
+// NullsFirst returns a Comparator for pointers that orders nil before any other
+// pointer, and compares the values of the non-nil pointers with cmp. Combined with
+// ComparingWith it sorts by optional fields.
+func NullsFirst[T any](cmp Comparator[T]) Comparator[*T] {
+	return nullsCompare(cmp, -1)
+}
+
+// NullsLast is like NullsFirst, but nil pointers are ordered after any other pointer.
+func NullsLast[T any](cmp Comparator[T]) Comparator[*T] {
+	return nullsCompare(cmp, +1)
+}
+
+// nullsCompare returns a Comparator for pointers where nil compares as nilOrder to
+// any non-nil pointer.
+func nullsCompare[T any](cmp Comparator[T], nilOrder int) Comparator[*T] {
+	return func(a, b *T) int {
+		switch {
+		case a == nil && b == nil:
+			return 0
+		case a == nil:
+			return nilOrder
+		case b == nil:
+			return -nilOrder
+		}
+		return cmp(*a, *b)
+	}
+}
+
 // SortSlice sorts the given slice according to the criteria in the provided comparator
 func SortSlice[T any](slice []T, comparator Comparator[T]) {
 	sort.Sort(&sorter[T]{items: slice, comparator: comparator})
 }
 
+// SortSliceStable is like SortSlice, but the elements that are equal according to
+// the comparator keep their original order.
+func SortSliceStable[T any](slice []T, comparator Comparator[T]) {
+	sort.Stable(&sorter[T]{items: slice, comparator: comparator})
+}
+
 type sorter[T any] struct {
 	items      []T
 	comparator Comparator[T]
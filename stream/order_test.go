@@ -0,0 +1,51 @@
+package stream
+
+import (
+	"reflect"
+	"testing"
+)
+
+type employee struct {
+	name string
+	dept string
+	age  *int
+}
+
+func intPtr(n int) *int { return &n }
+
+func TestComparators(t *testing.T) {
+	employees := []employee{
+		{"Carol", "ops", intPtr(40)},
+		{"Alice", "dev", nil},
+		{"Bob", "ops", intPtr(30)},
+		{"Dave", "dev", intPtr(25)},
+	}
+	names := func(cmp Comparator[employee]) []string {
+		sorted := append([]employee{}, employees...)
+		SortSliceStable(sorted, cmp)
+		return Map(OfSlice(sorted), func(e employee) string { return e.name }).ToSlice()
+	}
+	dept := func(e employee) string { return e.dept }
+	name := func(e employee) string { return e.name }
+	age := func(e employee) *int { return e.age }
+
+	tests := []struct {
+		name string
+		cmp  Comparator[employee]
+		want []string
+	}{
+		{"Comparing", Comparing(name), []string{"Alice", "Bob", "Carol", "Dave"}},
+		{"ThenComparingBy", ThenComparingBy(Comparing(dept), name), []string{"Alice", "Dave", "Bob", "Carol"}},
+		{"ThenComparing", Comparing(dept).ThenComparing(Inverse(Comparing(name))), []string{"Dave", "Alice", "Carol", "Bob"}},
+		{"NullsFirst", ComparingWith(age, NullsFirst(Natural[int])), []string{"Alice", "Dave", "Bob", "Carol"}},
+		{"NullsLast", ComparingWith(age, NullsLast(Natural[int])), []string{"Dave", "Bob", "Carol", "Alice"}},
+		{"stable", Comparing(dept), []string{"Alice", "Dave", "Carol", "Bob"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := names(tt.cmp); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("sorted = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
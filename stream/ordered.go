@@ -0,0 +1,347 @@
+package stream
+
+import (
+	"errors"
+	"runtime/debug"
+	"sync"
+	"sync/atomic"
+)
+
+// reorderWindow is the number of elements, per goroutine, that the goroutines of
+// an ordered parallel stream can process ahead of the next element to deliver.
+const reorderWindow = 4
+
+// seqItem holds the elements produced from the upstream element at position seq of
+// the encounter order. Filter may leave vals empty and FlatMap may grow it, but the
+// item is still delivered so the position is known to be processed.
+type seqItem[T any] struct {
+	seq  uint64
+	vals []T
+	// rest, if not nil, pulls the elements following vals on the goroutine consuming
+	// the stream, e.g. the contents of the streams mapped by FlatMap, so they are
+	// pulled lazily. Once exhausted, it returns the errors of these elements, which
+	// are reported before the next item is delivered. Only the stream built by
+	// flatMapSequenced has such items, and it doesn't expose its seqFn.
+	rest func() (T, bool, error)
+	// err is the error ending the stream after vals, reported when the item is
+	// delivered so the elements before it in encounter order are all delivered.
+	err error
+}
+
+// Ordered returns a stream that preserves the encounter order of its elements even
+// when it is parallel. Upstream elements are tagged with their position, the
+// following Map, Filter, FlatMap, Peek, MapErr and FilterErr stages process them
+// concurrently in Parallelism() goroutines, and a bounded reorder buffer
+// re-sequences them before they reach the next stage. The other operations (Limit,
+// Skip, Distinct...) and the terminal operations then see the elements in encounter
+// order, so for instance Limit(n) keeps the first n elements and FindFirst returns
+// the first one.
+func (s Stream[T]) Ordered() Stream[T] {
+	if s.ordered {
+		return s
+	}
+	c := s.config
+	c.ordered = true
+	return Stream[T]{
+		config: c,
+		nextFn: s.nextFn,
+		split:  s.split,
+	}
+}
+
+// ParallelOrdered is a shorthand for s.Parallel(p).Ordered().
+func (s Stream[T]) ParallelOrdered(p int) Stream[T] {
+	return s.Parallel(p).Ordered()
+}
+
+// sequenced returns the pull function of an ordered stream that tags the elements
+// with their position in the encounter order.
+func (s Stream[T]) sequenced() func() (seqItem[T], bool) {
+	if s.seqFn != nil {
+		return s.seqFn
+	}
+	var lock sync.Mutex
+	seq := uint64(0)
+	return func() (seqItem[T], bool) {
+		lock.Lock()
+		defer lock.Unlock()
+		v, hasNext := s.nextFn()
+		if !hasNext {
+			return seqItem[T]{}, false
+		}
+		item := seqItem[T]{seq: seq, vals: []T{v}}
+		seq++
+		return item, true
+	}
+}
+
+// mapSequenced returns an ordered stream that applies op to the elements produced
+// from every upstream element, concurrently if the stream is parallel.
+func mapSequenced[I, O any](s Stream[I], op func(vals []I) []O) Stream[O] {
+	seqFn := s.sequenced()
+	return fromSequenced(s.config, func() (seqItem[O], bool) {
+		item, hasNext := seqFn()
+		if !hasNext {
+			return seqItem[O]{}, false
+		}
+		return seqItem[O]{seq: item.seq, vals: op(item.vals), err: item.err}, true
+	})
+}
+
+// mapSequencedWhile is like mapSequenced, but op also returns an error to end the
+// stream after the elements it returned, e.g. a FailFast error. No more items are
+// pulled, but the items pulled before by the other goroutines, which come first in
+// encounter order, are still delivered before the error is reported.
+func mapSequencedWhile[I, O any](s Stream[I], op func(vals []I) ([]O, error)) Stream[O] {
+	seqFn := s.sequenced()
+	stopped := int32(0)
+	return fromSequenced(s.config, func() (seqItem[O], bool) {
+		if atomic.LoadInt32(&stopped) == 1 || s.pipe.isFailed() {
+			return seqItem[O]{}, false
+		}
+		item, hasNext := seqFn()
+		if !hasNext {
+			return seqItem[O]{}, false
+		}
+		vals, err := op(item.vals)
+		if err != nil {
+			atomic.StoreInt32(&stopped, 1)
+		} else {
+			err = item.err
+		}
+		return seqItem[O]{seq: item.seq, vals: vals, err: err}, true
+	})
+}
+
+// flatMapSequenced returns the ordered stream of the contents of the streams mapped
+// from the elements of s. Only mapper, and the stages before it, run in the
+// goroutines of s: the mapped streams are drained on the goroutine consuming the
+// stream, in encounter order, so they can be infinite. The errors of a mapped
+// stream are reported once its elements are delivered.
+func flatMapSequenced[IN, OUT any](s Stream[IN], mapper func(IN) Stream[OUT]) Stream[OUT] {
+	// open holds the mapped streams not closed yet, which are closed along with the
+	// pipeline
+	var lock sync.Mutex
+	open := make(map[*pipeline]struct{})
+	s.pipe.onClose(func() error {
+		lock.Lock()
+		pipes := open
+		open = nil
+		lock.Unlock()
+		var errs []error
+		for p := range pipes {
+			errs = append(errs, p.close())
+		}
+		return errors.Join(errs...)
+	})
+	track := func(out Stream[OUT]) {
+		lock.Lock()
+		closed := open == nil
+		if !closed {
+			open[out.pipe] = struct{}{}
+		}
+		lock.Unlock()
+		if closed {
+			out.pipe.close()
+		}
+	}
+	release := func(out Stream[OUT]) error {
+		lock.Lock()
+		delete(open, out.pipe)
+		lock.Unlock()
+		out.pipe.close()
+		return out.pipe.err()
+	}
+	// like mapSequencedWhile, but a Stream of the mapped streams would instantiate
+	// FlatMap recursively
+	seqFn := s.sequenced()
+	flattened := fromSequenced(s.config, func() (seqItem[OUT], bool) {
+		if s.pipe.isFailed() {
+			return seqItem[OUT]{}, false
+		}
+		item, hasNext := seqFn()
+		if !hasNext {
+			return seqItem[OUT]{}, false
+		}
+		outs := make([]Stream[OUT], len(item.vals))
+		for i, v := range item.vals {
+			outs[i] = mapper(v).orEmpty()
+			track(outs[i])
+		}
+		var errs []error
+		rest := func() (OUT, bool, error) {
+			for len(outs) > 0 {
+				if v, hasNext := outs[0].nextFn(); hasNext {
+					return v, true, nil
+				}
+				errs = append(errs, release(outs[0]))
+				outs = outs[1:]
+			}
+			var zeroVal OUT
+			return zeroVal, false, errors.Join(errs...)
+		}
+		return seqItem[OUT]{seq: item.seq, rest: rest, err: item.err}, true
+	})
+	// the following stages tag the flattened elements again
+	flattened.seqFn = nil
+	return flattened
+}
+
+// fromSequenced returns an ordered stream from a pull function of tagged elements.
+// Its nextFn re-sequences the elements, pulling seqFn from c.parallel goroutines,
+// and ends the stream at the first item carrying an error, which it reports to the
+// pipeline.
+func fromSequenced[T any](c config, seqFn func() (seqItem[T], bool)) Stream[T] {
+	var lock sync.Mutex
+	var once sync.Once
+	var buf *reorderBuffer[T]
+	var vals []T
+	var rest func() (T, bool, error)
+	var err error
+	ended := false
+	nextItem := func() (seqItem[T], bool) {
+		if c.parallel <= 1 {
+			return seqFn()
+		}
+		once.Do(func() {
+			buf = newReorderBuffer[T](c.parallel)
+			c.pipe.onClose(func() error { buf.stop(); return nil })
+			for i := 0; i < c.parallel; i++ {
+				go buf.work(seqFn)
+			}
+		})
+		return buf.take()
+	}
+	return Stream[T]{
+		config: c,
+		seqFn:  seqFn,
+		nextFn: func() (T, bool) {
+			lock.Lock()
+			defer lock.Unlock()
+			for len(vals) == 0 {
+				if rest != nil {
+					v, hasNext, restErr := rest()
+					if hasNext {
+						return v, true
+					}
+					rest = nil
+					if restErr != nil && !c.pipe.report(restErr) {
+						err, ended = nil, true
+					}
+				}
+				if err != nil {
+					c.pipe.report(err)
+					err, ended = nil, true
+				}
+				var zeroVal T
+				if ended {
+					return zeroVal, false
+				}
+				item, hasNext := nextItem()
+				if !hasNext {
+					return zeroVal, false
+				}
+				vals, rest, err = item.vals, item.rest, item.err
+			}
+			v := vals[0]
+			vals = vals[1:]
+			return v, true
+		},
+	}
+}
+
+// reorderBuffer collects the items processed by the goroutines of an ordered
+// parallel stream and delivers them in encounter order. A goroutine holding an item
+// too far ahead of the next one to deliver waits, so the buffer holds at most
+// window items.
+type reorderBuffer[T any] struct {
+	mu      sync.Mutex
+	cond    *sync.Cond
+	pending map[uint64]seqItem[T]
+	next    uint64 // position of the next item to deliver
+	window  uint64
+	workers int // goroutines still pulling items
+	stopped bool
+	perr    *PanicError
+}
+
+func newReorderBuffer[T any](parallel int) *reorderBuffer[T] {
+	b := &reorderBuffer[T]{
+		pending: make(map[uint64]seqItem[T]),
+		window:  uint64(reorderWindow * parallel),
+		workers: parallel,
+	}
+	b.cond = sync.NewCond(&b.mu)
+	return b
+}
+
+// work pulls items from seqFn and puts them in the buffer until seqFn is exhausted
+// or the buffer is stopped. A panic is recorded to be re-raised by take.
+func (b *reorderBuffer[T]) work(seqFn func() (seqItem[T], bool)) {
+	defer func() {
+		r := recover()
+		b.mu.Lock()
+		defer b.mu.Unlock()
+		if r != nil && b.perr == nil {
+			b.perr = &PanicError{Value: r, Stack: debug.Stack()}
+		}
+		b.workers--
+		b.cond.Broadcast()
+	}()
+	for {
+		b.mu.Lock()
+		stopped := b.stopped || b.perr != nil
+		b.mu.Unlock()
+		if stopped {
+			return
+		}
+		item, hasNext := seqFn()
+		if !hasNext || !b.put(item) {
+			return
+		}
+	}
+}
+
+func (b *reorderBuffer[T]) put(item seqItem[T]) bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	for item.seq >= b.next+b.window && !b.stopped && b.perr == nil {
+		b.cond.Wait()
+	}
+	if b.stopped || b.perr != nil {
+		return false
+	}
+	b.pending[item.seq] = item
+	b.cond.Broadcast()
+	return true
+}
+
+// take returns the next item in encounter order, or false once all the items have
+// been delivered. It re-raises the panic of any goroutine.
+func (b *reorderBuffer[T]) take() (seqItem[T], bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	for {
+		if b.perr != nil {
+			panic(b.perr)
+		}
+		if item, ok := b.pending[b.next]; ok {
+			delete(b.pending, b.next)
+			b.next++
+			b.cond.Broadcast()
+			return item, true
+		}
+		if b.workers == 0 || b.stopped {
+			return seqItem[T]{}, false
+		}
+		b.cond.Wait()
+	}
+}
+
+// stop makes the goroutines return after their current item.
+func (b *reorderBuffer[T]) stop() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.stopped = true
+	b.cond.Broadcast()
+}
@@ -0,0 +1,226 @@
+package stream
+
+import (
+	"errors"
+	"math/rand"
+	"reflect"
+	"runtime"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestStream_ParallelOrdered(t *testing.T) {
+	// jitter is measured so the parallel cases check that it runs concurrently; a
+	// new measure is stored for every case, as the goroutines of the previous one
+	// may still be finishing their current element
+	var running atomic.Pointer[inFlight]
+	running.Store(&inFlight{})
+	jitter := func(i int) int {
+		measure := running.Load()
+		measure.enter()
+		defer measure.leave()
+		time.Sleep(time.Duration(rand.Intn(50)) * time.Microsecond)
+		return i
+	}
+	tests := []struct {
+		name       string
+		s          Stream[int]
+		want       []int
+		sequential bool
+	}{
+		{
+			name: "map",
+			s:    Range(0, 1000).ParallelOrdered(8).Map(jitter),
+			want: Range(0, 1000).ToSlice(),
+		},
+		{
+			name: "filter",
+			s:    Range(0, 1000).ParallelOrdered(8).Map(jitter).Filter(func(i int) bool { return i%3 == 0 }),
+			want: Range(0, 1000).Filter(func(i int) bool { return i%3 == 0 }).ToSlice(),
+		},
+		{
+			name: "flat map",
+			s: Range(0, 100).ParallelOrdered(8).FlatMap(func(i int) Stream[int] {
+				return Of(jitter(2*i), 2*i+1)
+			}),
+			want: Range(0, 200).ToSlice(),
+		},
+		{
+			name: "limit",
+			s:    Range(0, 1000).ParallelOrdered(8).Map(jitter).Limit(10),
+			want: Range(0, 10).ToSlice(),
+		},
+		{
+			name: "skip then map",
+			s:    Range(0, 100).ParallelOrdered(8).Skip(90).Map(jitter),
+			want: Range(90, 100).ToSlice(),
+		},
+		{
+			name:       "sequential",
+			s:          Of(3, 1, 2).Ordered().Map(jitter),
+			want:       []int{3, 1, 2},
+			sequential: true,
+		},
+		{
+			name: "parallel after ordered stages",
+			s:    Range(0, 1000).Ordered().Map(jitter).Parallel(4),
+			want: Range(0, 1000).ToSlice(),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			measure := &inFlight{}
+			running.Store(measure)
+			got := tt.s.ToSlice()
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Stream.ToSlice() = %v, want %v", got, tt.want)
+			}
+			if concurrent := measure.maximum() > 1; concurrent == tt.sequential {
+				t.Errorf("stages ran in at most %d goroutine(s), sequential %v", measure.maximum(), tt.sequential)
+			}
+		})
+	}
+}
+
+func TestStream_ParallelOrdered_ForEach(t *testing.T) {
+	var slow inFlight
+	visited := []int{}
+	Range(0, 100).ParallelOrdered(4).Map(slowly(&slow, func(i int) int { return i * 2 })).ForEach(func(i int) {
+		visited = append(visited, i)
+	})
+	if want := Range(0, 100).Map(func(i int) int { return i * 2 }).ToSlice(); !reflect.DeepEqual(visited, want) {
+		t.Errorf("Stream.ForEach() visited = %v, want %v", visited, want)
+	}
+	if slow.maximum() < 2 {
+		t.Errorf("Map() ran in %d goroutine(s), want several", slow.maximum())
+	}
+}
+
+func TestStream_ParallelOrdered_EarlyStop(t *testing.T) {
+	before := runtime.NumGoroutine()
+	var slow inFlight
+	i := int64(0)
+	got, ok := Generate(func() (int, bool) {
+		return int(atomic.AddInt64(&i, 1)), true
+	}).ParallelOrdered(8).
+		Map(slowly(&slow, func(i int) int { return i * 10 })).
+		Filter(func(i int) bool { return i > 500 }).
+		FindFirst()
+	if !ok || got != 510 {
+		t.Errorf("Stream.FindFirst() = %v, %v, want 510, true", got, ok)
+	}
+	if slow.maximum() < 2 {
+		t.Errorf("Map() ran in %d goroutine(s), want several", slow.maximum())
+	}
+	checkGoroutines(t, before)
+}
+
+func TestStream_ParallelOrdered_Panic(t *testing.T) {
+	defer func() {
+		if _, ok := recover().(*PanicError); !ok {
+			t.Errorf("want a *PanicError")
+		}
+	}()
+	Range(0, 1000).ParallelOrdered(4).Map(func(i int) int {
+		if i == 500 {
+			panic("boom")
+		}
+		return i
+	}).ToSlice()
+}
+
+func TestStream_ParallelOrdered_Errors(t *testing.T) {
+	var slow inFlight
+	errOdd := errors.New("odd")
+	half := func(i int) (int, error) {
+		if i%2 == 1 {
+			return 0, errOdd
+		}
+		return i / 2, nil
+	}
+	notMultipleOf3 := func(i int) (bool, error) { return i%3 != 0, nil }
+	slowHalf := func(i int) (int, error) {
+		slow.enter()
+		defer slow.leave()
+		time.Sleep(time.Millisecond)
+		return half(i)
+	}
+	got, err := MapErr(Range(0, 200).ParallelOrdered(4), slowHalf).
+		WithErrorPolicy(SkipAndCollect).
+		FilterErr(notMultipleOf3).
+		ToSliceErr()
+	want := Range(0, 100).Filter(func(i int) bool { return i%3 != 0 }).ToSlice()
+	if !reflect.DeepEqual(got, want) || !errors.Is(err, errOdd) {
+		t.Errorf("ToSliceErr() = %v, %v, want %v and %v", got, err, want, errOdd)
+	}
+	if slow.maximum() < 2 {
+		t.Errorf("MapErr() ran in %d goroutine(s), want several", slow.maximum())
+	}
+
+	got, err = MapErr(Range(0, 1000).ParallelOrdered(4), half).ToSliceErr()
+	if !errors.Is(err, errOdd) || !reflect.DeepEqual(got, []int{0}) {
+		t.Errorf("FailFast: ToSliceErr() = %v, %v, want [0] and %v", got, err, errOdd)
+	}
+
+	// the elements before the failing one are all delivered, whichever goroutine
+	// fails first
+	errAt := errors.New("failed at 1000")
+	got, err = MapErr(Range(0, 100000).ParallelOrdered(8), func(i int) (int, error) {
+		if i >= 1000 {
+			return 0, errAt
+		}
+		return i, nil
+	}).ToSliceErr()
+	if want := Range(0, 1000).ToSlice(); !reflect.DeepEqual(got, want) || !errors.Is(err, errAt) {
+		t.Errorf("FailFast: ToSliceErr() = %d elements, %v, want %d and %v", len(got), err, len(want), errAt)
+	}
+	got, err = Range(0, 100000).ParallelOrdered(8).FilterErr(func(i int) (bool, error) {
+		if i >= 1000 {
+			return false, errAt
+		}
+		return true, nil
+	}).ToSliceErr()
+	if want := Range(0, 1000).ToSlice(); !reflect.DeepEqual(got, want) || !errors.Is(err, errAt) {
+		t.Errorf("FailFast: FilterErr ToSliceErr() = %d elements, %v, want %d and %v", len(got), err, len(want), errAt)
+	}
+}
+
+func TestStream_ParallelOrdered_FlatMap(t *testing.T) {
+	naturals := func(int) Stream[int] {
+		n := 0
+		return Generate(func() (int, bool) { n++; return n, true })
+	}
+	for _, p := range []int{1, 4} {
+		got := Of(3, 1, 2).ParallelOrdered(p).FlatMap(naturals).Limit(3).ToSlice()
+		if want := []int{1, 2, 3}; !reflect.DeepEqual(got, want) {
+			t.Errorf("parallel %d: FlatMap(infinite).Limit(3) = %v, want %v", p, got, want)
+		}
+	}
+
+	var created, closed int32
+	got := Range(0, 100).ParallelOrdered(4).FlatMap(func(i int) Stream[int] {
+		atomic.AddInt32(&created, 1)
+		return Of(i, i).OnClose(func() error { atomic.AddInt32(&closed, 1); return nil })
+	}).Limit(7).ToSlice()
+	if want := []int{0, 0, 1, 1, 2, 2, 3}; !reflect.DeepEqual(got, want) {
+		t.Errorf("FlatMap().Limit(7) = %v, want %v", got, want)
+	}
+	if created, closed := atomic.LoadInt32(&created), atomic.LoadInt32(&closed); created != closed {
+		t.Errorf("closed %d of the %d mapped streams", closed, created)
+	}
+
+	// the error of a mapped stream is reported once the elements before it are delivered
+	errAt := errors.New("failed at 150")
+	got, err := FlatMap(Range(0, 200).ParallelOrdered(4), func(i int) Stream[int] {
+		return MapErr(Of(i), func(i int) (int, error) {
+			if i == 150 {
+				return 0, errAt
+			}
+			return i, nil
+		})
+	}).ToSliceErr()
+	if want := Range(0, 150).ToSlice(); !reflect.DeepEqual(got, want) || !errors.Is(err, errAt) {
+		t.Errorf("FailFast: ToSliceErr() = %d elements, %v, want %d and %v", len(got), err, len(want), errAt)
+	}
+}
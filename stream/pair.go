@@ -0,0 +1,34 @@
+package stream
+
+// Pair holds two values of possibly different types, such as the key and value
+// of a map entry.
+type Pair[A, B any] struct {
+	First  A
+	Second B
+}
+
+// PairOf returns a Pair holding first and second.
+func PairOf[A, B any](first A, second B) Pair[A, B] {
+	return Pair[A, B]{First: first, Second: second}
+}
+
+// Optional holds a value that may be absent, such as the missing elements of the
+// shorter stream in ZipLongest.
+type Optional[T any] struct {
+	Value T
+	// Ok reports whether Value is present.
+	Ok bool
+}
+
+// Some returns an Optional holding v.
+func Some[T any](v T) Optional[T] {
+	return Optional[T]{Value: v, Ok: true}
+}
+
+// OrElse returns the value of the Optional if it is present, or v otherwise.
+func (o Optional[T]) OrElse(v T) T {
+	if o.Ok {
+		return o.Value
+	}
+	return v
+}
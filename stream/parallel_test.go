@@ -0,0 +1,134 @@
+package stream
+
+import (
+	"context"
+	"reflect"
+	"sort"
+	"strconv"
+	"sync/atomic"
+	"testing"
+)
+
+// TestParallelOperators runs every intermediate operation both sequentially and in
+// parallel, and is meant to be run with the race detector (go test -race).
+func TestParallelOperators(t *testing.T) {
+	const size = 10000
+	input := func() Stream[int] {
+		return Range(0, size)
+	}
+	tests := []struct {
+		name string
+		op   func(s Stream[int]) Stream[int]
+		// want is the sorted result of the operation, or nil if only the number of
+		// elements (wantLen) is deterministic for parallel streams
+		want    []int
+		wantLen int
+	}{
+		{
+			name: "Filter",
+			op:   func(s Stream[int]) Stream[int] { return s.Filter(func(i int) bool { return i%3 == 0 }) },
+			want: Range(0, size).Filter(func(i int) bool { return i%3 == 0 }).ToSlice(),
+		},
+		{
+			name:    "FilterN",
+			op:      func(s Stream[int]) Stream[int] { return s.FilterN(100, func(i int) bool { return i%3 == 0 }) },
+			wantLen: 100,
+		},
+		{
+			name: "Map",
+			op:   func(s Stream[int]) Stream[int] { return s.Map(func(i int) int { return i * 2 }) },
+			want: Range(0, size).Map(func(i int) int { return i * 2 }).ToSlice(),
+		},
+		{
+			name: "Take",
+			op:   func(s Stream[int]) Stream[int] { return s.Take(100) },
+			want: Range(0, 100).ToSlice(),
+		},
+		{
+			name:    "Skip",
+			op:      func(s Stream[int]) Stream[int] { return s.Skip(100) },
+			wantLen: size - 100,
+		},
+		{
+			name: "Distinct",
+			op:   func(s Stream[int]) Stream[int] { return Distinct(s.Map(func(i int) int { return i % 100 })) },
+			want: Range(0, 100).ToSlice(),
+		},
+		{
+			name: "Sorted",
+			op:   func(s Stream[int]) Stream[int] { return s.Sorted(Inverse(Natural[int])) },
+			want: Range(0, size).ToSlice(),
+		},
+		{
+			name: "FlatMap",
+			op: func(s Stream[int]) Stream[int] {
+				return s.FlatMap(func(i int) Stream[int] { return Of(i, i+size) })
+			},
+			want: Range(0, 2*size).ToSlice(),
+		},
+		{
+			name: "Peek",
+			op: func(s Stream[int]) Stream[int] {
+				peeked := int64(0)
+				return s.Peek(func(int) { atomic.AddInt64(&peeked, 1) })
+			},
+			want: Range(0, size).ToSlice(),
+		},
+		{
+			name: "Concat",
+			op:   func(s Stream[int]) Stream[int] { return Concat(s, Range(size, 2*size)) },
+			want: Range(0, 2*size).ToSlice(),
+		},
+		{
+			name: "WithContext",
+			op:   func(s Stream[int]) Stream[int] { return s.WithContext(context.Background()) },
+			want: Range(0, size).ToSlice(),
+		},
+		{
+			name: "MapErr",
+			op: func(s Stream[int]) Stream[int] {
+				return MapErr(Map(s, strconv.Itoa), strconv.Atoi)
+			},
+			want: Range(0, size).ToSlice(),
+		},
+		{
+			name: "FilterErr",
+			op: func(s Stream[int]) Stream[int] {
+				return s.FilterErr(func(i int) (bool, error) { return i%3 == 0, nil })
+			},
+			want: Range(0, size).Filter(func(i int) bool { return i%3 == 0 }).ToSlice(),
+		},
+		{
+			name: "Chunk",
+			op:   func(s Stream[int]) Stream[int] { return FlatMap(Chunk(s, 7), OfSlice[int]) },
+			want: Range(0, size).ToSlice(),
+		},
+		{
+			name:    "Window",
+			op:      func(s Stream[int]) Stream[int] { return Map(Window(s, 5, 2), func(w []int) int { return len(w) }) },
+			wantLen: (size-5)/2 + 1,
+		},
+		{
+			name:    "Pairwise",
+			op:      func(s Stream[int]) Stream[int] { return Map(Pairwise(s), func(p [2]int) int { return p[0] }) },
+			wantLen: size - 1,
+		},
+	}
+	for _, tt := range tests {
+		for _, p := range []int{1, 8} {
+			t.Run(tt.name+"/parallel "+strconv.Itoa(p), func(t *testing.T) {
+				got := tt.op(input().Parallel(p)).ToSlice()
+				if tt.want == nil {
+					if len(got) != tt.wantLen {
+						t.Errorf("got %d elements, want %d", len(got), tt.wantLen)
+					}
+					return
+				}
+				sort.Ints(got)
+				if !reflect.DeepEqual(got, tt.want) {
+					t.Errorf("got %d elements, want %d", len(got), len(tt.want))
+				}
+			})
+		}
+	}
+}
@@ -0,0 +1,221 @@
+package stream
+
+import (
+	"errors"
+	"sync"
+	"sync/atomic"
+)
+
+// pipeline holds the run-time state shared by all the stages derived from the same
+// source: the error policy, the errors reported by the error-aware operators and
+// the functions releasing the resources (e.g. goroutines) used by the stages.
+// Operators that combine several streams (e.g. Concat) join their pipelines.
+type pipeline struct {
+	mu        sync.Mutex
+	policy    ErrorPolicy
+	policySet bool
+	errs      []error
+	failed    int32
+	closers   []func() error
+	closed    bool
+	// closeErr holds the errors returned by the closers.
+	closeErr error
+	parents  []*pipeline
+	// upstream is the pipeline of the source of a branch of Tee. Its errors are
+	// visible from the branch, but closing the branch doesn't close it.
+	upstream *pipeline
+}
+
+func newPipeline() *pipeline {
+	return &pipeline{}
+}
+
+// joinPipelines returns a pipeline whose state includes the state of all the given
+// pipelines.
+func joinPipelines(parents ...*pipeline) *pipeline {
+	return &pipeline{parents: parents}
+}
+
+func (p *pipeline) setPolicy(policy ErrorPolicy) {
+	if p == nil {
+		return
+	}
+	p.mu.Lock()
+	p.policy, p.policySet = policy, true
+	p.mu.Unlock()
+	for _, parent := range p.parentList() {
+		parent.setPolicy(policy)
+	}
+}
+
+// addParent joins parent to the pipeline after its creation, e.g. the pipeline of
+// the stream returned by the factory of Defer. The policy set on the pipeline, if
+// any, applies to parent; otherwise the pipeline adopts the one set on parent. The
+// parent is closed right away if the pipeline is already closed.
+func (p *pipeline) addParent(parent *pipeline) {
+	p.mu.Lock()
+	p.parents = append(p.parents, parent)
+	policy, policySet, closed := p.policy, p.policySet, p.closed
+	p.mu.Unlock()
+	if policySet {
+		parent.setPolicy(policy)
+	} else {
+		parent.mu.Lock()
+		policy, policySet = parent.policy, parent.policySet
+		parent.mu.Unlock()
+		if policySet {
+			p.mu.Lock()
+			p.policy, p.policySet = policy, true
+			p.mu.Unlock()
+		}
+	}
+	if closed {
+		parent.close()
+	}
+}
+
+// parentList returns the parents of the pipeline, which addParent may extend
+// concurrently.
+func (p *pipeline) parentList() []*pipeline {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.parents
+}
+
+// report records err according to the error policy of the pipeline. It returns
+// true if the element that caused the error must be skipped, or false if the
+// stream must stop.
+func (p *pipeline) report(err error) bool {
+	p.mu.Lock()
+	policy := p.policy
+	switch {
+	case policy.handler != nil:
+	case policy.skip:
+		p.errs = append(p.errs, err)
+	case atomic.LoadInt32(&p.failed) == 0:
+		p.errs = append(p.errs, err)
+		atomic.StoreInt32(&p.failed, 1)
+	}
+	p.mu.Unlock()
+	if policy.handler != nil {
+		policy.handler(err)
+	}
+	return policy.skip
+}
+
+// skips returns whether the error policy of the pipeline skips the elements that
+// failed, so that report never stops the stream.
+func (p *pipeline) skips() bool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.policy.skip
+}
+
+// isFailed returns whether an error stopped the pipeline or any of its parents.
+func (p *pipeline) isFailed() bool {
+	if p == nil {
+		return false
+	}
+	if atomic.LoadInt32(&p.failed) == 1 {
+		return true
+	}
+	for _, parent := range p.parentList() {
+		if parent.isFailed() {
+			return true
+		}
+	}
+	return p.upstream.isFailed()
+}
+
+// err returns the errors recorded by the pipeline and its parents, including the
+// errors of their closers, or nil.
+func (p *pipeline) err() error {
+	if p == nil {
+		return nil
+	}
+	p.mu.Lock()
+	errs := append([]error{}, p.errs...)
+	if p.closeErr != nil {
+		errs = append(errs, p.closeErr)
+	}
+	p.mu.Unlock()
+	for _, parent := range p.parentList() {
+		if err := parent.err(); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	if err := p.upstream.err(); err != nil {
+		errs = append(errs, err)
+	}
+	return errors.Join(errs...)
+}
+
+// onClose registers fn to be invoked when the pipeline is closed, or invokes it
+// right away if the pipeline is already closed.
+func (p *pipeline) onClose(fn func() error) {
+	p.mu.Lock()
+	if p.closed {
+		p.mu.Unlock()
+		p.runClosers([]func() error{fn})
+		return
+	}
+	p.closers = append(p.closers, fn)
+	p.mu.Unlock()
+}
+
+// close invokes the functions registered with onClose, in reverse order of
+// registration, and closes the parent pipelines. Terminal operations close the
+// pipeline of their stream once they are done, even if they panic. Closing is
+// idempotent. The errors returned by the functions are recorded, so err reports
+// them, and returned joined with the ones of the parents. If some of the functions
+// panic, the others still run and the first panic is re-raised at the end.
+func (p *pipeline) close() (err error) {
+	if p == nil {
+		return nil
+	}
+	defer func() {
+		for _, parent := range p.parentList() {
+			err = errors.Join(err, parent.close())
+		}
+	}()
+	p.mu.Lock()
+	if p.closed {
+		defer p.mu.Unlock()
+		return p.closeErr
+	}
+	p.closed = true
+	closers := p.closers
+	p.closers = nil
+	p.mu.Unlock()
+	p.runClosers(closers)
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.closeErr
+}
+
+// runClosers invokes the closers in reverse order and records their errors.
+func (p *pipeline) runClosers(closers []func() error) {
+	var errs []error
+	var panicked bool
+	var panicVal any
+	for i := len(closers) - 1; i >= 0; i-- {
+		func() {
+			defer func() {
+				if r := recover(); r != nil && !panicked {
+					panicked, panicVal = true, r
+				}
+			}()
+			if err := closers[i](); err != nil {
+				errs = append(errs, err)
+			}
+		}()
+	}
+	if len(errs) > 0 {
+		p.mu.Lock()
+		p.closeErr = errors.Join(append([]error{p.closeErr}, errs...)...)
+		p.mu.Unlock()
+	}
+	if panicked {
+		panic(panicVal)
+	}
+}
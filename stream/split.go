@@ -0,0 +1,170 @@
+package stream
+
+import "sync"
+
+// maxSplitBatch is the maximum number of indexes a goroutine claims at once from
+// its own part of a split source. Claimed indexes can't be stolen, so smaller
+// batches balance better and larger batches lock less often.
+const maxSplitBatch = 64
+
+// splitFn returns n pull functions over disjoint parts of a stream, one for each
+// of the goroutines of a parallel terminal operation, or nil if the stream is not
+// worth splitting. Each pull function is only invoked from one goroutine.
+type splitFn[T any] func(n int) []func() (T, bool)
+
+// splitIndexed returns the splitFn of a sized source of size elements, where the
+// element at index i is at(i). The indexes are divided in n equal parts, and a
+// goroutine that exhausts its part steals half of the largest remaining part, so
+// imbalanced workloads keep all the goroutines busy.
+// Sources with less than maxSplitBatch elements per goroutine are not split, as
+// sharing them costs less than splitting them.
+func splitIndexed[T any](size int, at func(i int) T) splitFn[T] {
+	return func(n int) []func() (T, bool) {
+		if size < n*maxSplitBatch {
+			return nil
+		}
+		sp := newSplitter(size, n)
+		batch := min(max(size/(8*n), 1), maxSplitBatch)
+		nexts := make([]func() (T, bool), n)
+		for i := range nexts {
+			i := i
+			cur, end := 0, 0
+			nexts[i] = func() (T, bool) {
+				if cur >= end {
+					lo, hi, ok := sp.claim(i, batch)
+					if !ok {
+						var zeroVal T
+						return zeroVal, false
+					}
+					cur, end = lo, hi
+				}
+				v := at(cur)
+				cur++
+				return v, true
+			}
+		}
+		return nexts
+	}
+}
+
+// splitWith returns the splitFn of the stream resulting from applying op to the
+// pull function of each part of s, or nil if s can't be split. It can be used by
+// the operations that don't share any state between elements.
+func splitWith[I, O any](s Stream[I], op func(next func() (I, bool)) func() (O, bool)) splitFn[O] {
+	if s.split == nil {
+		return nil
+	}
+	return func(n int) []func() (O, bool) {
+		parts := s.split(n)
+		if parts == nil {
+			return nil
+		}
+		nexts := make([]func() (O, bool), len(parts))
+		for i, part := range parts {
+			nexts[i] = op(part)
+		}
+		return nexts
+	}
+}
+
+// stateless returns the stream resulting from applying op to the pull function of s.
+// The pull functions returned by op must not share any state, so op can also be
+// applied to each part of s when it can be split.
+func stateless[I, O any](s Stream[I], op func(next func() (I, bool)) func() (O, bool)) Stream[O] {
+	return Stream[O]{
+		config: s.config,
+		nextFn: op(s.nextFn),
+		split:  splitWith(s, op),
+	}
+}
+
+// pullers returns a pull function for each of the n goroutines consuming the
+// stream: its own part if the stream can be split, or the shared pull function
+// of the stream otherwise.
+func (s Stream[T]) pullers(n int) []func() (T, bool) {
+	if s.split != nil {
+		if parts := s.split(n); parts != nil {
+			return parts
+		}
+	}
+	nexts := make([]func() (T, bool), n)
+	for i := range nexts {
+		nexts[i] = s.nextFn
+	}
+	return nexts
+}
+
+// indexRange is the part [lo, hi) of the indexes of a source owned by a goroutine.
+type indexRange struct {
+	mu     sync.Mutex
+	lo, hi int
+}
+
+// splitter divides the indexes [0, size) of a source between several goroutines.
+type splitter struct {
+	parts []indexRange
+}
+
+func newSplitter(size, n int) *splitter {
+	sp := &splitter{parts: make([]indexRange, n)}
+	for i := range sp.parts {
+		sp.parts[i].lo = size * i / n
+		sp.parts[i].hi = size * (i + 1) / n
+	}
+	return sp
+}
+
+// claim returns up to batch indexes from the front of the part i. If the part is
+// exhausted it first steals the back half of the largest other part. It returns
+// false once there is nothing left to steal.
+func (sp *splitter) claim(i, batch int) (lo, hi int, ok bool) {
+	own := &sp.parts[i]
+	for {
+		own.mu.Lock()
+		if own.lo < own.hi {
+			lo, hi = own.lo, min(own.hi, own.lo+batch)
+			own.lo = hi
+			own.mu.Unlock()
+			return lo, hi, true
+		}
+		own.mu.Unlock()
+		lo, hi, ok = sp.steal(i)
+		if !ok {
+			return 0, 0, false
+		}
+		own.mu.Lock()
+		own.lo, own.hi = lo, hi
+		own.mu.Unlock()
+	}
+}
+
+// steal removes the back half of the largest part other than i and returns it.
+func (sp *splitter) steal(i int) (lo, hi int, ok bool) {
+	for {
+		victim, largest := -1, 0
+		for j := range sp.parts {
+			if j == i {
+				continue
+			}
+			p := &sp.parts[j]
+			p.mu.Lock()
+			if p.hi-p.lo > largest {
+				victim, largest = j, p.hi-p.lo
+			}
+			p.mu.Unlock()
+		}
+		if victim < 0 {
+			return 0, 0, false
+		}
+		p := &sp.parts[victim]
+		p.mu.Lock()
+		if remaining := p.hi - p.lo; remaining > 0 {
+			lo, hi = p.hi-(remaining+1)/2, p.hi
+			p.hi = lo
+			p.mu.Unlock()
+			return lo, hi, true
+		}
+		// the part was exhausted in the meantime, look for another one
+		p.mu.Unlock()
+	}
+}
@@ -0,0 +1,75 @@
+package stream
+
+import (
+	"reflect"
+	"sort"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestSplitIndexed(t *testing.T) {
+	const size, n = 10000, 8
+	parts := splitIndexed(size, func(i int) int { return i })(n)
+	if len(parts) != n {
+		t.Fatalf("got %d parts, want %d", len(parts), n)
+	}
+	var wg sync.WaitGroup
+	got := make([][]int, n)
+	for i, next := range parts {
+		i, next := i, next
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for v, ok := next(); ok; v, ok = next() {
+				if i == 0 {
+					// a slow worker: the others must steal its elements
+					time.Sleep(10 * time.Microsecond)
+				}
+				got[i] = append(got[i], v)
+			}
+		}()
+	}
+	wg.Wait()
+
+	all := []int{}
+	for _, part := range got {
+		all = append(all, part...)
+	}
+	sort.Ints(all)
+	if !reflect.DeepEqual(all, Range(0, size).ToSlice()) {
+		t.Errorf("the parts don't cover every index exactly once: got %d indexes", len(all))
+	}
+	if len(got[0]) >= size/n {
+		t.Errorf("the slow worker processed %d elements, want less than %d", len(got[0]), size/n)
+	}
+}
+
+func TestSplitIndexed_Small(t *testing.T) {
+	if parts := splitIndexed(10, func(i int) int { return i })(4); parts != nil {
+		t.Errorf("got %d parts for a small source, want nil", len(parts))
+	}
+}
+
+func TestStream_Split(t *testing.T) {
+	tests := []struct {
+		name      string
+		s         Stream[int]
+		splitable bool
+	}{
+		{name: "slice", s: OfSlice(randomSlice(1000)), splitable: true},
+		{name: "range", s: Range(0, 1000), splitable: true},
+		{name: "map", s: Range(0, 1000).Map(func(i int) int { return i }), splitable: true},
+		{name: "filter", s: Range(0, 1000).Filter(func(i int) bool { return true }), splitable: true},
+		{name: "flat map", s: Range(0, 1000).FlatMap(func(i int) Stream[int] { return Of(i) }), splitable: true},
+		{name: "limit", s: Range(0, 1000).Limit(10), splitable: false},
+		{name: "generate", s: Generate(func() (int, bool) { return 0, true }), splitable: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.s.Parallel(4).split != nil; got != tt.splitable {
+				t.Errorf("splitable = %v, want %v", got, tt.splitable)
+			}
+		})
+	}
+}
@@ -0,0 +1,219 @@
+package stream
+
+import (
+	"runtime/debug"
+	"sync"
+)
+
+// Backpressure defines what Tee does with an element when the buffer of a branch
+// that hasn't consumed the previous elements yet is full.
+type Backpressure int
+
+const (
+	// Block makes the source wait until the slowest branch has room for the element,
+	// so no branch misses any element. The branches must then be consumed
+	// concurrently, e.g. with Fork, or the first one to fill its buffer blocks the
+	// others forever.
+	Block Backpressure = iota
+	// DropNewest doesn't deliver the element to the branches whose buffer is full.
+	DropNewest
+	// DropOldest discards the oldest buffered element of the branches whose buffer is
+	// full to make room for the element.
+	DropOldest
+)
+
+// Tee returns n streams that all yield the elements of the input stream, which is
+// pulled only once. Every branch buffers up to bufSize elements (at least 1) that
+// it hasn't consumed yet, and policy defines what happens to the elements that
+// don't fit. The source is pulled by the branches that need a new element, one at
+// a time, so the elements are delivered in the same order to all the branches; each
+// branch can be made parallel as any other stream.
+// A branch is detached when its terminal operation is done, so it no longer holds
+// back the other branches, and the input stream is closed when all the branches are.
+func Tee[T any](s Stream[T], n, bufSize int, policy Backpressure) []Stream[T] {
+	t := &tee[T]{
+		next:    s.nextFn,
+		bufSize: max(bufSize, 1),
+		policy:  policy,
+		queues:  make([][]T, n),
+		active:  make([]bool, n),
+		open:    n,
+		source:  s.pipe,
+	}
+	t.cond = sync.NewCond(&t.mu)
+	branches := make([]Stream[T], n)
+	for i := range branches {
+		i := i
+		t.active[i] = true
+		pipe := newPipeline()
+		pipe.upstream = s.pipe
+		pipe.onClose(func() error { t.detach(i); return nil })
+		branches[i] = Stream[T]{
+			config: config{parallel: 1, pipe: pipe, clock: s.clock},
+			nextFn: func() (T, bool) { return t.pull(i) },
+		}
+	}
+	return branches
+}
+
+// Fork feeds the input stream, pulled only once, to all the branches, which run
+// concurrently in their own goroutine, and returns their results in the order of the
+// branches, e.g.
+//
+//	res := Fork(s, 64, Count[int], func(s Stream[int]) int { return s.Reduce(0, add) })
+//
+// The branches are created with Tee and the Block policy, so every branch sees all
+// the elements. If a branch panics, the panic is re-raised as a *PanicError once the
+// other branches are done.
+func Fork[T, R any](s Stream[T], bufSize int, branches ...func(Stream[T]) R) []R {
+	streams := Tee(s, len(branches), bufSize, Block)
+	res := make([]R, len(branches))
+	var wg sync.WaitGroup
+	var once sync.Once
+	var perr *PanicError
+	for i, branch := range branches {
+		i, branch := i, branch
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			defer func() {
+				if r := recover(); r != nil {
+					once.Do(func() { perr = asPanicError(r) })
+				}
+			}()
+			// closing the branch if it didn't run a terminal operation detaches it,
+			// so it doesn't hold back the others
+			defer streams[i].pipe.close()
+			res[i] = branch(streams[i])
+		}()
+	}
+	wg.Wait()
+	if perr != nil {
+		panic(perr)
+	}
+	return res
+}
+
+// asPanicError returns r as a *PanicError, wrapping it with the current stack if it
+// isn't one already.
+func asPanicError(r any) *PanicError {
+	if perr, ok := r.(*PanicError); ok {
+		return perr
+	}
+	return &PanicError{Value: r, Stack: debug.Stack()}
+}
+
+// tee broadcasts the elements of a source to several branches. The branch that needs
+// an element that none of the others pulled yet pulls it from the source, and queues
+// it for the other active branches.
+type tee[T any] struct {
+	mu      sync.Mutex
+	cond    *sync.Cond
+	next    func() (T, bool)
+	bufSize int
+	policy  Backpressure
+	queues  [][]T
+	active  []bool
+	open    int  // branches still active
+	pulling bool // a branch is pulling the source
+	done    bool // the source is exhausted
+	perr    *PanicError
+	source  *pipeline
+}
+
+func (t *tee[T]) pull(i int) (T, bool) {
+	var zeroVal T
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	for {
+		if t.perr != nil {
+			panic(t.perr)
+		}
+		if q := t.queues[i]; len(q) > 0 {
+			v := q[0]
+			t.queues[i] = q[1:]
+			t.cond.Broadcast()
+			return v, true
+		}
+		if t.done || !t.active[i] {
+			return zeroVal, false
+		}
+		if t.pulling || !t.hasRoom(i) {
+			t.cond.Wait()
+			continue
+		}
+		v, hasNext := t.pullSource()
+		if !hasNext {
+			continue
+		}
+		for j, q := range t.queues {
+			if j == i || !t.active[j] {
+				continue
+			}
+			if len(q) >= t.bufSize {
+				if t.policy == DropNewest {
+					continue
+				}
+				// only DropOldest gets here, Block waited for room
+				q = q[1:]
+			}
+			t.queues[j] = append(q, v)
+		}
+		t.cond.Broadcast()
+		return v, true
+	}
+}
+
+// hasRoom returns whether the source can be pulled on behalf of the branch i, which
+// under the Block policy requires all the other active branches to have room for
+// one more element.
+func (t *tee[T]) hasRoom(i int) bool {
+	if t.policy != Block {
+		return true
+	}
+	for j, q := range t.queues {
+		if j != i && t.active[j] && len(q) >= t.bufSize {
+			return false
+		}
+	}
+	return true
+}
+
+// pullSource pulls the source without holding the lock, so the other branches can
+// consume their buffers meanwhile. A panic of the source is re-raised in all the
+// branches.
+func (t *tee[T]) pullSource() (v T, hasNext bool) {
+	t.pulling = true
+	t.mu.Unlock()
+	defer func() {
+		t.mu.Lock()
+		t.pulling = false
+		if r := recover(); r != nil {
+			t.perr = asPanicError(r)
+		} else if !hasNext {
+			t.done = true
+		}
+		t.cond.Broadcast()
+	}()
+	return t.next()
+}
+
+// detach removes the branch i, and closes the source once all the branches are
+// detached.
+func (t *tee[T]) detach(i int) {
+	t.mu.Lock()
+	if !t.active[i] {
+		t.mu.Unlock()
+		return
+	}
+	t.active[i] = false
+	t.queues[i] = nil
+	t.open--
+	last := t.open == 0
+	t.cond.Broadcast()
+	t.mu.Unlock()
+	if last {
+		// the errors of the source are reported by the branches as upstream errors
+		t.source.close()
+	}
+}
@@ -0,0 +1,108 @@
+package stream
+
+import (
+	"reflect"
+	"runtime"
+	"sync"
+	"testing"
+)
+
+func TestFork(t *testing.T) {
+	before := runtime.NumGoroutine()
+	pulls := 0
+	source := Generate(func() (int, bool) { pulls++; return pulls, pulls <= 1000 })
+	res := Fork(source, 8,
+		Count[int],
+		func(s Stream[int]) int { return s.Parallel(4).Reduce(0, func(a, b int) int { return a + b }) },
+		func(s Stream[int]) int { return s.Filter(func(n int) bool { return n%2 == 0 }).Count() },
+		func(s Stream[int]) int { v, _ := s.Skip(10).FindFirst(); return v },
+	)
+	if want := []int{1000, 500500, 500, 11}; !reflect.DeepEqual(res, want) {
+		t.Errorf("Fork() = %v, want %v", res, want)
+	}
+	if pulls != 1001 {
+		t.Errorf("source pulled %d times, want 1001", pulls)
+	}
+	checkGoroutines(t, before)
+}
+
+func TestFork_Panic(t *testing.T) {
+	defer func() {
+		perr, ok := recover().(*PanicError)
+		if !ok || perr.Value != "boom" {
+			t.Errorf("recovered %v, want a *PanicError of boom", perr)
+		}
+	}()
+	Fork(Range(0, 100), 4,
+		Count[int],
+		func(s Stream[int]) int {
+			s.ForEach(func(n int) {
+				if n == 50 {
+					panic("boom")
+				}
+			})
+			return 0
+		},
+	)
+	t.Errorf("Fork() didn't panic")
+}
+
+func TestTee(t *testing.T) {
+	t.Run("block", func(t *testing.T) {
+		branches := Tee(Range(0, 100), 3, 2, Block)
+		res := make([][]int, len(branches))
+		var wg sync.WaitGroup
+		for i, b := range branches {
+			i, b := i, b
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				res[i] = b.ToSlice()
+			}()
+		}
+		wg.Wait()
+		want := Range(0, 100).ToSlice()
+		for i := range res {
+			if !reflect.DeepEqual(res[i], want) {
+				t.Errorf("branch %d = %v, want %v", i, res[i], want)
+			}
+		}
+	})
+	t.Run("drop", func(t *testing.T) {
+		tests := []struct {
+			policy Backpressure
+			want   []int
+		}{
+			{DropNewest, []int{0, 1}},
+			{DropOldest, []int{8, 9}},
+		}
+		for _, tt := range tests {
+			branches := Tee(Range(0, 10), 2, 2, tt.policy)
+			if got := branches[0].ToSlice(); !reflect.DeepEqual(got, Range(0, 10).ToSlice()) {
+				t.Errorf("policy %d: first branch = %v", tt.policy, got)
+			}
+			if got := branches[1].ToSlice(); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("policy %d: second branch = %v, want %v", tt.policy, got, tt.want)
+			}
+		}
+	})
+	t.Run("detach", func(t *testing.T) {
+		closed := false
+		source := Range(0, 1000)
+		source.pipe.onClose(func() error { closed = true; return nil })
+		branches := Tee(source, 2, 8, Block)
+		// the first branch stops after 3 elements, and must not block the second one
+		if got := branches[0].Limit(3).ToSlice(); !reflect.DeepEqual(got, []int{0, 1, 2}) {
+			t.Errorf("first branch = %v", got)
+		}
+		if closed {
+			t.Errorf("source closed before all the branches")
+		}
+		if got := branches[1].Count(); got != 1000 {
+			t.Errorf("second branch count = %d, want 1000", got)
+		}
+		if !closed {
+			t.Errorf("source not closed after all the branches")
+		}
+	})
+}
@@ -1,10 +1,51 @@
 package stream
 
 import (
+	"context"
+	"runtime/debug"
 	"sync"
 	"sync/atomic"
 )
 
+// runParallel runs worker in s.parallel goroutines and waits for all of them to
+// finish. Each worker pulls elements with the given next function, which is its own
+// part of the stream if the stream can be split. If a worker
+// panics, next stops returning elements to the other workers, and the panic is
+// returned as a *PanicError so it can be re-raised on the calling goroutine.
+func (s Stream[T]) runParallel(worker func(next func() (T, bool))) *PanicError {
+	var wg sync.WaitGroup
+	wg.Add(s.parallel)
+	var once sync.Once
+	var perr *PanicError
+	panicked := int32(0)
+	guard := func(next func() (T, bool)) func() (T, bool) {
+		return func() (T, bool) {
+			if atomic.LoadInt32(&panicked) == 1 {
+				var zeroVal T
+				return zeroVal, false
+			}
+			return next()
+		}
+	}
+	for _, next := range s.pullers(s.parallel) {
+		next := guard(next)
+		go func() {
+			defer wg.Done()
+			defer func() {
+				if r := recover(); r != nil {
+					once.Do(func() {
+						perr = &PanicError{Value: r, Stack: debug.Stack()}
+					})
+					atomic.StoreInt32(&panicked, 1)
+				}
+			}()
+			worker(next)
+		}()
+	}
+	wg.Wait()
+	return perr
+}
+
 // --- terminals
 // ForEach invokes the consumer function for each item of the stream.
 // This function is equivalent to invoking input.ForEach(consumer) as method.
@@ -13,36 +54,54 @@ import (
 // as doing so would sacrifice the benefit of parallelism. For any given element,
 // the action may be performed at whatever time and in whatever thread the library chooses.
 // If the action accesses shared state, it is responsible for providing the required synchronization.
-func ForEach[T any](input stream[T], consumer func(T)) {
+func ForEach[T any](input Stream[T], consumer func(T)) {
 	input.ForEach(consumer)
 }
 
-func (s stream[T]) ForEach(consumer func(T)) {
-	if s.parallel <= 1 {
+func (s Stream[T]) ForEach(consumer func(T)) {
+	defer s.pipe.close()
+	if !s.concurrent() {
 		next := s.nextFn
 		for in, ok := next(); ok; in, ok = next() {
 			consumer(in)
 		}
 		return
 	}
-	var wg sync.WaitGroup
-	wg.Add(s.parallel)
-	for i := 0; i < s.parallel; i++ {
-		go func() {
-			defer wg.Done()
-			next := s.nextFn
-			for in, ok := next(); ok; in, ok = next() {
-				consumer(in)
-			}
-		}()
+	perr := s.runParallel(func(next func() (T, bool)) {
+		for in, ok := next(); ok; in, ok = next() {
+			consumer(in)
+		}
+	})
+	if perr != nil {
+		panic(perr)
 	}
-	wg.Wait()
 }
 
-// terminal operation
-func (s stream[T]) ToSlice() []T {
+// ForEachCtx is like ForEach but stops invoking the consumer as soon as ctx is done.
+// It returns ctx.Err() if the cancellation cut the stream short, or nil if all the
+// elements were consumed. See WithContext for details about cancellation.
+func ForEachCtx[T any](ctx context.Context, input Stream[T], consumer func(T)) error {
+	return input.ForEachCtx(ctx, consumer)
+}
+
+func (s Stream[T]) ForEachCtx(ctx context.Context, consumer func(T)) error {
+	cs, err := cancelable(s, ctx)
+	cs.ForEach(consumer)
+	return err()
+}
+
+// ToSlice returns a slice with all the elements of the stream. For ordered streams
+// the elements are in encounter order.
+func (s Stream[T]) ToSlice() []T {
+	defer s.pipe.close()
+	return s.toSlice()
+}
+
+// toSlice is ToSlice without closing the pipeline, for the operations that need to
+// drain their input stream.
+func (s Stream[T]) toSlice() []T {
 	//quick path for sequential stream
-	if s.parallel <= 1 {
+	if !s.concurrent() {
 		res := []T{}
 		for {
 			v, hasNext := s.nextFn()
@@ -52,26 +111,18 @@ func (s stream[T]) ToSlice() []T {
 			res = append(res, v)
 		}
 	}
-	var wg sync.WaitGroup
-	wg.Add(s.parallel)
-
 	resCh := make(chan T, s.parallel)
-
-	for i := 0; i < s.parallel; i++ {
-		go func() {
-			defer wg.Done()
+	var perr *PanicError
+	go func() {
+		perr = s.runParallel(func(next func() (T, bool)) {
 			for {
-				v, hasNext := s.nextFn()
+				v, hasNext := next()
 				if !hasNext {
 					return
 				}
 				resCh <- v
 			}
-		}()
-	}
-
-	go func() {
-		wg.Wait()
+		})
 		close(resCh)
 	}()
 
@@ -79,16 +130,28 @@ func (s stream[T]) ToSlice() []T {
 	for v := range resCh {
 		res = append(res, v)
 	}
+	if perr != nil {
+		panic(perr)
+	}
 	return res
 }
 
-func (s stream[T]) ReduceSequentially(initial T, fn func(T, T) T) T {
+// ToSliceCtx is like ToSlice but stops collecting as soon as ctx is done, in which
+// case it returns the elements collected so far along with ctx.Err().
+func (s Stream[T]) ToSliceCtx(ctx context.Context) ([]T, error) {
+	cs, err := cancelable(s, ctx)
+	res := cs.ToSlice()
+	return res, err()
+}
+
+func (s Stream[T]) ReduceSequentially(initial T, fn func(T, T) T) T {
 	return ReduceSequentially[T, T](s, initial, fn)
 }
 
 // ReduceSequentially Performs a reduction on the elements of this stream, using the provided identity value and an associative accumulation function, and returns the reduced value.
 // The identity value must be an identity for the accumulator function. This means that for all t, accumulator.apply(identity, t) is equal to t. The accumulator function must be an associative function.
-func ReduceSequentially[I any, O any](s stream[I], identity O, accumulator func(O, I) O) O {
+func ReduceSequentially[I any, O any](s Stream[I], identity O, accumulator func(O, I) O) O {
+	defer s.pipe.close()
 	for {
 		v, hasNext := s.nextFn()
 		if !hasNext {
@@ -101,65 +164,82 @@ func ReduceSequentially[I any, O any](s stream[I], identity O, accumulator func(
 
 // the identity element is both an initial seed value for the reduction and a default result if there are no input elements. The accumulator function takes a partial result and the next element, and produces a new partial result.
 // The combiner function combines two partial results to produce a new partial result.
-func Reduce[I any, O any](s stream[I], identity O, accumulator func(O, I) O, combiner func(O, O) O) O {
+func Reduce[I any, O any](s Stream[I], identity O, accumulator func(O, I) O, combiner func(O, O) O) O {
+	defer s.pipe.close()
+	return reduce(s, func() O { return identity }, accumulator, combiner)
+}
+
+// reduce is Reduce without closing the pipeline, taking a supplier of the initial
+// value of each worker so the partial results can be mutable containers.
+func reduce[I any, O any](s Stream[I], supplier func() O, accumulator func(O, I) O, combiner func(O, O) O) O {
 	//quick path for sequential stream
-	if s.parallel <= 1 {
+	if !s.concurrent() {
+		res := supplier()
 		for {
 			v, hasNext := s.nextFn()
 			if !hasNext {
-				return identity
+				return res
 			}
-			identity = accumulator(identity, v)
+			res = accumulator(res, v)
 		}
 	}
-	var wg sync.WaitGroup
-	wg.Add(s.parallel)
-
+	// every worker sends exactly one partial result, so sending never blocks
 	resCh := make(chan O, s.parallel)
-
-	for i := 0; i < s.parallel; i++ {
-		go func() {
-			defer wg.Done()
-			res := identity
-			for {
-				v, hasNext := s.nextFn()
-				if !hasNext {
-					resCh <- res
-					return
-				}
-				res = accumulator(res, v)
+	perr := s.runParallel(func(next func() (I, bool)) {
+		res := supplier()
+		for {
+			v, hasNext := next()
+			if !hasNext {
+				resCh <- res
+				return
 			}
-		}()
+			res = accumulator(res, v)
+		}
+	})
+	close(resCh)
+	if perr != nil {
+		panic(perr)
 	}
 
-	go func() {
-		wg.Wait()
-		close(resCh)
-	}()
-
-	res := identity
+	res, ok := <-resCh
+	if !ok {
+		return supplier()
+	}
 	for v := range resCh {
 		res = combiner(res, v)
 	}
 	return res
 }
 
-func (s stream[T]) Reduce(initial T, fn func(T, T) T) T {
+func (s Stream[T]) Reduce(initial T, fn func(T, T) T) T {
 	return Reduce[T, T](s, initial, fn, fn)
 }
 
+// ReduceCtx is like Reduce but stops accumulating as soon as ctx is done, in which
+// case it returns the reduction of the elements consumed so far along with ctx.Err().
+func ReduceCtx[I any, O any](ctx context.Context, s Stream[I], identity O, accumulator func(O, I) O, combiner func(O, O) O) (O, error) {
+	cs, err := cancelable(s, ctx)
+	res := Reduce(cs, identity, accumulator, combiner)
+	return res, err()
+}
+
+func (s Stream[T]) ReduceCtx(ctx context.Context, initial T, fn func(T, T) T) (T, error) {
+	return ReduceCtx[T, T](ctx, s, initial, fn, fn)
+}
+
 // AllMatch returns whether all elements of this stream match the provided predicate.
 // If this operation finds an item where the predicate is false, it stops processing
 // the rest of the stream.
 // If the stream is empty then true is returned and the predicate is not evaluated.
 // This function is equivalent to invoking input.AllMatch(predicate) as method
-func AllMatch[T any](input stream[T], predicate func(T) bool) bool {
+func AllMatch[T any](input Stream[T], predicate func(T) bool) bool {
 	return input.AllMatch(predicate)
 }
 
-func (s stream[T]) AllMatch(predicate func(T) bool) bool {
+func (s Stream[T]) AllMatch(predicate func(T) bool) bool {
+	defer s.pipe.close()
 	next := s.nextFn
-	if s.parallel <= 1 {
+	if !s.concurrent() {
 		for r, ok := next(); ok; r, ok = next() {
 			if !predicate(r) {
 				return false
@@ -167,42 +247,51 @@ func (s stream[T]) AllMatch(predicate func(T) bool) bool {
 		}
 		return true
 	}
-	var wg sync.WaitGroup
-	wg.Add(s.parallel)
 	done := int32(0)  // 0: not done, 1: done; for short circuit
 	match := int32(1) // 0: not match, 1: match
-	for i := 0; i < s.parallel; i++ {
-		go func() {
-			defer wg.Done()
-			for r, ok := next(); ok; r, ok = next() {
-				if atomic.LoadInt32(&done) == 1 {
-					// short circuit
-					return
-				}
-				if !predicate(r) {
-					atomic.StoreInt32(&match, 0)
-					atomic.StoreInt32(&done, 1)
-					return
-				}
+	perr := s.runParallel(func(next func() (T, bool)) {
+		for r, ok := next(); ok; r, ok = next() {
+			if atomic.LoadInt32(&done) == 1 {
+				// short circuit
+				return
 			}
-		}()
+			if !predicate(r) {
+				atomic.StoreInt32(&match, 0)
+				atomic.StoreInt32(&done, 1)
+				return
+			}
+		}
+	})
+	if perr != nil {
+		panic(perr)
 	}
-	wg.Wait()
 	return atomic.LoadInt32(&match) == 1
 }
 
+// AllMatchCtx is like AllMatch but stops evaluating the predicate as soon as ctx is
+// done, in which case it returns false along with ctx.Err().
+func (s Stream[T]) AllMatchCtx(ctx context.Context, predicate func(T) bool) (bool, error) {
+	cs, err := cancelable(s, ctx)
+	res := cs.AllMatch(predicate)
+	if err := err(); err != nil {
+		return false, err
+	}
+	return res, nil
+}
+
 // AnyMatch returns whether any elements of this stream match the provided predicate.
 // If this operation finds an item where the predicate is true, it stops processing
 // the rest of the stream.
 // If the stream is empty then false is returned and the predicate is not evaluated.
 // This function is equivalent to invoking input.AnyMatch(predicate) as method
-func AnyMatch[T any](input stream[T], predicate func(T) bool) bool {
+func AnyMatch[T any](input Stream[T], predicate func(T) bool) bool {
 	return input.AnyMatch(predicate)
 }
 
-func (s stream[T]) AnyMatch(predicate func(T) bool) bool {
+func (s Stream[T]) AnyMatch(predicate func(T) bool) bool {
+	defer s.pipe.close()
 	next := s.nextFn
-	if s.parallel <= 1 {
+	if !s.concurrent() {
 		for r, ok := next(); ok; r, ok = next() {
 			if predicate(r) {
 				return true
@@ -210,49 +299,68 @@ func (s stream[T]) AnyMatch(predicate func(T) bool) bool {
 		}
 		return false
 	}
-	var wg sync.WaitGroup
-	wg.Add(s.parallel)
 	done := int32(0)  // 0: not done, 1: done; for short circuit
 	match := int32(0) // 0: not match, 1: match
-	for i := 0; i < s.parallel; i++ {
-		go func() {
-			defer wg.Done()
-			for r, ok := next(); ok; r, ok = next() {
-				if atomic.LoadInt32(&done) == 1 {
-					// short circuit
-					return
-				}
-				if predicate(r) {
-					atomic.StoreInt32(&match, 1)
-					atomic.StoreInt32(&done, 1)
-					return
-				}
+	perr := s.runParallel(func(next func() (T, bool)) {
+		for r, ok := next(); ok; r, ok = next() {
+			if atomic.LoadInt32(&done) == 1 {
+				// short circuit
+				return
 			}
-		}()
+			if predicate(r) {
+				atomic.StoreInt32(&match, 1)
+				atomic.StoreInt32(&done, 1)
+				return
+			}
+		}
+	})
+	if perr != nil {
+		panic(perr)
 	}
-	wg.Wait()
 	return atomic.LoadInt32(&match) == 1
 }
 
+// AnyMatchCtx is like AnyMatch but stops evaluating the predicate as soon as ctx is
+// done. If a matching element was found before the cancellation it returns true and
+// nil, otherwise it returns false along with ctx.Err().
+func (s Stream[T]) AnyMatchCtx(ctx context.Context, predicate func(T) bool) (bool, error) {
+	cs, err := cancelable(s, ctx)
+	if cs.AnyMatch(predicate) {
+		return true, nil
+	}
+	return false, err()
+}
+
 // NoneMatch returns whether no elements of this stream match the provided predicate.
 // If this operation finds an item where the predicate is true, it stops processing
 // the rest of the stream.
 // This function is equivalent to invoking input.NoneMatch(predicate) as method.
-func NoneMatch[T any](input stream[T], predicate func(T) bool) bool {
+func NoneMatch[T any](input Stream[T], predicate func(T) bool) bool {
 	return input.NoneMatch(predicate)
 }
 
-func (is stream[T]) NoneMatch(predicate func(T) bool) bool {
+func (is Stream[T]) NoneMatch(predicate func(T) bool) bool {
 	return !is.AnyMatch(predicate)
 }
 
+// NoneMatchCtx is the negation of AnyMatchCtx: it returns false and nil if a
+// matching element was found, and false along with ctx.Err() if ctx is done before
+// the stream is exhausted.
+func (s Stream[T]) NoneMatchCtx(ctx context.Context, predicate func(T) bool) (bool, error) {
+	res, err := s.AnyMatchCtx(ctx, predicate)
+	if err != nil {
+		return false, err
+	}
+	return !res, nil
+}
+
 // Count of elements in this stream.
-func Count[T any](input stream[T]) int {
+func Count[T any](input Stream[T]) int {
 	return input.Count()
 }
 
 // the count of elements in this stream
-func (s stream[T]) Count() int {
+func (s Stream[T]) Count() int {
 	// use Reduce to count
 	return Reduce[T, int](s, 0,
 		func(acc int, in T) int { return acc + 1 },
@@ -261,12 +369,32 @@ func (s stream[T]) Count() int {
 		})
 }
 
+// CountCtx is like Count but stops counting as soon as ctx is done, in which case
+// it returns the number of elements counted so far along with ctx.Err().
+func (s Stream[T]) CountCtx(ctx context.Context) (int, error) {
+	cs, err := cancelable(s, ctx)
+	res := cs.Count()
+	return res, err()
+}
+
+// Close invokes the close handlers of the stream (see OnClose), unless a terminal
+// operation already did, and returns their errors joined. It releases the resources
+// of a stream that is not going to be consumed.
+func (s Stream[T]) Close() error {
+	return s.pipe.close()
+}
+
 // Concat creates a lazily concatenated stream whose elements are all the elements of the first stream followed by all the elements of the second stream.
 // The resulting stream is ordered if both of the input streams are ordered, and parallel if either of the input streams is parallel.
 // When the resulting stream is closed, the close handlers for both input streams are invoked.
-func Concat[T any](s1, s2 stream[T]) stream[T] {
-	return stream[T]{
-		parallel: max(s1.parallel, s2.parallel),
+func Concat[T any](s1, s2 Stream[T]) Stream[T] {
+	return Stream[T]{
+		config: config{
+			parallel: max(s1.parallel, s2.parallel),
+			ordered:  s1.ordered && s2.ordered,
+			pipe:     joinPipelines(s1.pipe, s2.pipe),
+			clock:    s1.clock,
+		},
 		nextFn: func() (T, bool) {
 			v, hasNext := s1.nextFn()
 			if hasNext {
@@ -281,11 +409,12 @@ func Concat[T any](s1, s2 stream[T]) stream[T] {
 // along with true if the stream is not empty. If the stream is empty, returns the zero
 // value along with false.
 // This function is equivalent to invoking input.Max(cmp) as method.
-func Max[T any](input stream[T], cmp Comparator[T]) (T, bool) {
+func Max[T any](input Stream[T], cmp Comparator[T]) (T, bool) {
 	return input.Max(cmp)
 }
 
-func (s stream[T]) Max(cmp Comparator[T]) (T, bool) {
+func (s Stream[T]) Max(cmp Comparator[T]) (T, bool) {
+	defer s.pipe.close()
 	next := s.nextFn
 	var max T
 	for n, ok := next(); ok; n, ok = next() {
@@ -300,11 +429,12 @@ func (s stream[T]) Max(cmp Comparator[T]) (T, bool) {
 // along with true if the stream is not empty. If the stream is empty, returns the zero
 // value along with false.
 // This function is equivalent to invoking input.Min(cmp) as method.
-func Min[T any](input stream[T], cmp Comparator[T]) (T, bool) {
+func Min[T any](input Stream[T], cmp Comparator[T]) (T, bool) {
 	return input.Min(cmp)
 }
 
-func (s stream[T]) Min(cmp Comparator[T]) (T, bool) {
+func (s Stream[T]) Min(cmp Comparator[T]) (T, bool) {
+	defer s.pipe.close()
 	next := s.nextFn
 	var min T
 	for n, ok := next(); ok; n, ok = next() {
@@ -314,3 +444,17 @@ func (s stream[T]) Min(cmp Comparator[T]) (T, bool) {
 	}
 	return min, true
 }
+
+// FindFirst returns the first element of the stream along with true, or the zero
+// value along with false if the stream is empty. For ordered streams it is the first
+// element in encounter order; for parallel streams that are not ordered it may be
+// any element.
+// This function is equivalent to invoking input.FindFirst() as method.
+func FindFirst[T any](input Stream[T]) (T, bool) {
+	return input.FindFirst()
+}
+
+func (s Stream[T]) FindFirst() (T, bool) {
+	defer s.pipe.close()
+	return s.nextFn()
+}
@@ -1,14 +1,19 @@
 package stream
 
 import (
+	"context"
+	"errors"
 	"reflect"
+	"runtime"
+	"sync/atomic"
 	"testing"
+	"time"
 )
 
 func TestStream_ForEach(t *testing.T) {
 	tests := []struct {
 		name string
-		s    stream[int]
+		s    Stream[int]
 		want []int
 	}{
 		{
@@ -36,7 +41,7 @@ func TestStream_ForEach(t *testing.T) {
 func TestStream_ToSlice(t *testing.T) {
 	tests := []struct {
 		name string
-		s    stream[int]
+		s    Stream[int]
 		want []int
 	}{
 		{
@@ -72,7 +77,7 @@ func TestStream_ReduceSequentially(t *testing.T) {
 	}
 	tests := []struct {
 		name string
-		s    stream[int]
+		s    Stream[int]
 		args args
 		want int
 	}{
@@ -106,7 +111,7 @@ func TestStream_Reduce(t *testing.T) {
 	}
 	tests := []struct {
 		name string
-		s    stream[int]
+		s    Stream[int]
 		args args
 		want int
 	}{
@@ -139,7 +144,7 @@ func TestStream_AllMatch(t *testing.T) {
 	}
 	tests := []struct {
 		name string
-		s    stream[int]
+		s    Stream[int]
 		args args
 		want bool
 	}{
@@ -201,3 +206,122 @@ func TestStream_AllMatch(t *testing.T) {
 		})
 	}
 }
+
+func TestStream_ForEachCtx(t *testing.T) {
+	for _, p := range []int{1, 8} {
+		before := runtime.NumGoroutine()
+		ctx, cancel := context.WithCancel(context.Background())
+		visited := int64(0)
+		err := Generate(func() (int, bool) { return 1, true }).
+			Parallel(p).
+			ForEachCtx(ctx, func(int) {
+				if atomic.AddInt64(&visited, 1) == 100 {
+					cancel()
+				}
+			})
+		if !errors.Is(err, context.Canceled) {
+			t.Errorf("parallel %d: Stream.ForEachCtx() error = %v, want %v", p, err, context.Canceled)
+		}
+		if n := atomic.LoadInt64(&visited); n < 100 {
+			t.Errorf("parallel %d: visited %d elements, want at least 100", p, n)
+		}
+		checkGoroutines(t, before)
+	}
+}
+
+func TestStream_ToSliceCtx(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	_, err := Generate(func() (int, bool) { return 1, true }).Parallel(8).ToSliceCtx(ctx)
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Stream.ToSliceCtx() error = %v, want %v", err, context.DeadlineExceeded)
+	}
+
+	got, err := OfSlice([]int{1, 2, 3}).ToSliceCtx(context.Background())
+	if err != nil || !reflect.DeepEqual(got, []int{1, 2, 3}) {
+		t.Errorf("Stream.ToSliceCtx() = %v, %v, want %v, nil", got, err, []int{1, 2, 3})
+	}
+}
+
+func TestStream_ReduceCtx(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	got, err := Range(1, 101).Parallel(4).ReduceCtx(ctx, 0, func(a, b int) int { return a + b })
+	if err != nil || got != 5050 {
+		t.Errorf("Stream.ReduceCtx() = %v, %v, want 5050, nil", got, err)
+	}
+
+	cancel()
+	_, err = Range(1, 101).ReduceCtx(ctx, 0, func(a, b int) int { return a + b })
+	if !errors.Is(err, context.Canceled) {
+		t.Errorf("Stream.ReduceCtx() error = %v, want %v", err, context.Canceled)
+	}
+}
+
+func TestStream_AnyMatchCtx(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	found, err := Generate(func() (int, bool) { return 1, true }).
+		Parallel(4).
+		AnyMatchCtx(ctx, func(i int) bool { return i > 1 })
+	if found || !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Stream.AnyMatchCtx() = %v, %v, want false, %v", found, err, context.DeadlineExceeded)
+	}
+}
+
+func TestStream_ParallelPanic(t *testing.T) {
+	panicky := func() Stream[int] {
+		i := int64(0)
+		return Generate(func() (int, bool) {
+			return int(atomic.AddInt64(&i, 1)), true
+		}).Parallel(4).Map(func(i int) int {
+			if i == 50 {
+				panic("boom")
+			}
+			return i
+		})
+	}
+	tests := []struct {
+		name     string
+		terminal func(s Stream[int])
+	}{
+		{name: "ForEach", terminal: func(s Stream[int]) { s.ForEach(func(int) {}) }},
+		{name: "ToSlice", terminal: func(s Stream[int]) { s.ToSlice() }},
+		{name: "Reduce", terminal: func(s Stream[int]) { s.Reduce(0, func(a, b int) int { return a + b }) }},
+		{name: "Count", terminal: func(s Stream[int]) { s.Count() }},
+		{name: "AllMatch", terminal: func(s Stream[int]) { s.AllMatch(func(i int) bool { return i > 0 }) }},
+		{name: "AnyMatch", terminal: func(s Stream[int]) { s.AnyMatch(func(i int) bool { return i < 0 }) }},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			before := runtime.NumGoroutine()
+			var recovered any
+			func() {
+				defer func() { recovered = recover() }()
+				tt.terminal(panicky())
+			}()
+			perr, ok := recovered.(*PanicError)
+			if !ok {
+				t.Fatalf("recovered %v, want a *PanicError", recovered)
+			}
+			if perr.Value != "boom" || len(perr.Stack) == 0 {
+				t.Errorf("PanicError = {%v, %d bytes of stack}, want {boom, non empty stack}", perr.Value, len(perr.Stack))
+			}
+			checkGoroutines(t, before)
+		})
+	}
+}
+
+// checkGoroutines fails the test if the number of goroutines does not go back to
+// at most before within a second.
+func checkGoroutines(t *testing.T, before int) {
+	t.Helper()
+	deadline := time.Now().Add(time.Second)
+	for runtime.NumGoroutine() > before {
+		if time.Now().After(deadline) {
+			t.Errorf("%d goroutines leaked", runtime.NumGoroutine()-before)
+			return
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
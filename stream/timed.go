@@ -0,0 +1,308 @@
+package stream
+
+import (
+	"errors"
+	"sync"
+	"time"
+)
+
+// ErrTimeout is the error reported by Timeout when the next element takes too long.
+var ErrTimeout = errors.New("stream: timeout waiting for the next element")
+
+// Clock is the source of time of the time-based operators (BufferTime, Throttle,
+// Debounce, Sample, Timeout...). SystemClock is used unless another one is set with
+// WithClock, e.g. a fake clock advanced by hand in tests.
+type Clock interface {
+	Now() time.Time
+	// NewTimer returns a Timer that sends the current time on its channel after at
+	// least d, or right away if d is not positive.
+	NewTimer(d time.Duration) Timer
+}
+
+// Timer is a single-shot timer created by a Clock.
+type Timer interface {
+	C() <-chan time.Time
+	// Stop prevents the timer from firing, and returns false if it already fired.
+	Stop() bool
+}
+
+// SystemClock is the Clock backed by the time package.
+var SystemClock Clock = systemClock{}
+
+type systemClock struct{}
+
+func (systemClock) Now() time.Time { return time.Now() }
+
+func (systemClock) NewTimer(d time.Duration) Timer { return systemTimer{time.NewTimer(d)} }
+
+type systemTimer struct{ t *time.Timer }
+
+func (t systemTimer) C() <-chan time.Time { return t.t.C }
+
+func (t systemTimer) Stop() bool { return t.t.Stop() }
+
+// WithClock returns the stream with the Clock used by the time-based operators that
+// follow it.
+func (s Stream[T]) WithClock(clock Clock) Stream[T] {
+	s.clock = clock
+	return s
+}
+
+// timeSource returns the Clock of the stream.
+func (c config) timeSource() Clock {
+	if c.clock == nil {
+		return SystemClock
+	}
+	return c.clock
+}
+
+// pump pulls the elements of a stream from a goroutine, started on the first pull,
+// and sends them through a channel, so the time-based operators can wait for the
+// next element and for a timer at once. The goroutine stops when the pipeline is
+// closed; if it is blocked pulling the source at that time (e.g. an OfChannel
+// source waiting for a value) it returns once that pull does.
+type pump[T any] struct {
+	s     Stream[T]
+	clock Clock
+	once  sync.Once
+	ch    chan T
+	perr  *PanicError
+}
+
+func newPump[T any](s Stream[T]) *pump[T] {
+	return &pump[T]{s: s, clock: s.timeSource()}
+}
+
+func (p *pump[T]) start() {
+	p.ch = make(chan T)
+	done := make(chan struct{})
+	p.s.pipe.onClose(func() error { close(done); return nil })
+	go func() {
+		defer close(p.ch)
+		defer func() {
+			if r := recover(); r != nil {
+				p.perr = asPanicError(r)
+			}
+		}()
+		for {
+			v, hasNext := p.s.nextFn()
+			if !hasNext {
+				return
+			}
+			select {
+			case p.ch <- v:
+			case <-done:
+				return
+			}
+		}
+	}()
+}
+
+// next returns the next element of the stream, or timedOut if deadline is not zero
+// and passes first. It re-raises the panic of the source.
+func (p *pump[T]) next(deadline time.Time) (v T, hasNext, timedOut bool) {
+	p.once.Do(p.start)
+	var timeout <-chan time.Time
+	if !deadline.IsZero() {
+		timer := p.clock.NewTimer(deadline.Sub(p.clock.Now()))
+		defer timer.Stop()
+		timeout = timer.C()
+	}
+	select {
+	case v, hasNext = <-p.ch:
+		if !hasNext && p.perr != nil {
+			panic(p.perr)
+		}
+		return v, hasNext, false
+	case <-timeout:
+		return v, true, true
+	}
+}
+
+// BufferTime returns a stream of the elements of the input stream grouped in the
+// batches received during consecutive periods of d, starting on the first pull. The
+// periods without any element don't produce an empty batch, and the last batch holds
+// the elements received since the previous one when the input stream ends.
+// The input stream is pulled from its own goroutine, so a batch is emitted on time
+// even while the source is blocked (e.g. an OfChannel source waiting for a value).
+func BufferTime[T any](s Stream[T], d time.Duration) Stream[[]T] {
+	return BufferCountOrTime(s, 0, d)
+}
+
+// BufferCountOrTime is like BufferTime, but a batch is also emitted as soon as it
+// holds size elements, in which case the next period starts right away. A size of 0
+// or less doesn't limit the batches.
+func BufferCountOrTime[T any](s Stream[T], size int, d time.Duration) Stream[[]T] {
+	p := newPump(s)
+	var lock sync.Mutex
+	var deadline time.Time
+	done := false
+	return Stream[[]T]{
+		config: s.config,
+		nextFn: func() ([]T, bool) {
+			lock.Lock()
+			defer lock.Unlock()
+			if done {
+				return nil, false
+			}
+			if deadline.IsZero() {
+				deadline = p.clock.Now().Add(d)
+			}
+			var batch []T
+			for {
+				v, hasNext, timedOut := p.next(deadline)
+				switch {
+				case timedOut:
+					deadline = deadline.Add(d)
+					if len(batch) > 0 {
+						return batch, true
+					}
+				case !hasNext:
+					done = true
+					return batch, len(batch) > 0
+				default:
+					batch = append(batch, v)
+					if size > 0 && len(batch) >= size {
+						deadline = p.clock.Now().Add(d)
+						return batch, true
+					}
+				}
+			}
+		},
+	}
+}
+
+// Throttle returns a stream that emits an element of the input stream and then drops
+// the following ones for a period of d.
+func Throttle[T any](s Stream[T], d time.Duration) Stream[T] {
+	clock := s.timeSource()
+	var lock sync.Mutex
+	var last time.Time
+	emitted := false
+	return Stream[T]{
+		config: s.config,
+		nextFn: func() (T, bool) {
+			lock.Lock()
+			defer lock.Unlock()
+			for {
+				v, hasNext := s.nextFn()
+				if !hasNext {
+					return v, false
+				}
+				now := clock.Now()
+				if !emitted || now.Sub(last) >= d {
+					last, emitted = now, true
+					return v, true
+				}
+			}
+		},
+	}
+}
+
+// Debounce returns a stream that emits an element of the input stream only once d
+// has passed without receiving another element, so of a burst of elements only the
+// last one is emitted. When the input stream ends, its pending last element is
+// emitted right away.
+func Debounce[T any](s Stream[T], d time.Duration) Stream[T] {
+	p := newPump(s)
+	var lock sync.Mutex
+	done := false
+	return Stream[T]{
+		config: s.config,
+		nextFn: func() (T, bool) {
+			lock.Lock()
+			defer lock.Unlock()
+			var pending T
+			var deadline time.Time // zero while there is no pending element
+			for !done {
+				v, hasNext, timedOut := p.next(deadline)
+				switch {
+				case timedOut:
+					return pending, true
+				case !hasNext:
+					done = true
+					if !deadline.IsZero() {
+						return pending, true
+					}
+				default:
+					pending, deadline = v, p.clock.Now().Add(d)
+				}
+			}
+			var zeroVal T
+			return zeroVal, false
+		},
+	}
+}
+
+// Sample returns a stream that emits, at the end of consecutive periods of d starting
+// on the first pull, the last element received from the input stream during the
+// period, if any. When the input stream ends, the last element received since the
+// previous emission is emitted right away.
+func Sample[T any](s Stream[T], d time.Duration) Stream[T] {
+	p := newPump(s)
+	var lock sync.Mutex
+	var deadline time.Time
+	done := false
+	return Stream[T]{
+		config: s.config,
+		nextFn: func() (T, bool) {
+			lock.Lock()
+			defer lock.Unlock()
+			if deadline.IsZero() {
+				deadline = p.clock.Now().Add(d)
+			}
+			var latest T
+			received := false
+			for !done {
+				v, hasNext, timedOut := p.next(deadline)
+				switch {
+				case timedOut:
+					deadline = deadline.Add(d)
+					if received {
+						return latest, true
+					}
+				case !hasNext:
+					done = true
+					if received {
+						return latest, true
+					}
+				default:
+					latest, received = v, true
+				}
+			}
+			var zeroVal T
+			return zeroVal, false
+		},
+	}
+}
+
+// Timeout returns a stream that ends if the next element of the input stream takes
+// longer than d to arrive after it was requested, e.g. because a live source stalled.
+// The ErrTimeout error is then reported to the pipeline, so the error-aware terminals
+// (ToSliceErr, ForEachErr) return it according to the ErrorPolicy, but the stream
+// ends whatever the policy.
+func Timeout[T any](s Stream[T], d time.Duration) Stream[T] {
+	p := newPump(s)
+	var lock sync.Mutex
+	done := false
+	return Stream[T]{
+		config: s.config,
+		nextFn: func() (T, bool) {
+			lock.Lock()
+			defer lock.Unlock()
+			var zeroVal T
+			if done {
+				return zeroVal, false
+			}
+			v, hasNext, timedOut := p.next(p.clock.Now().Add(d))
+			if timedOut {
+				s.pipe.report(ErrTimeout)
+			}
+			if timedOut || !hasNext {
+				done = true
+				return zeroVal, false
+			}
+			return v, true
+		},
+	}
+}
@@ -0,0 +1,234 @@
+package stream
+
+import (
+	"errors"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+)
+
+// fakeClock is a Clock whose time only moves with advance. waitTimers lets a test
+// wait until the operator under test has started waiting for a given timer, so the
+// tests are deterministic.
+type fakeClock struct {
+	mu      sync.Mutex
+	cond    *sync.Cond
+	now     time.Time
+	created int
+	timers  []*fakeTimer
+}
+
+type fakeTimer struct {
+	clock *fakeClock
+	when  time.Time
+	ch    chan time.Time
+	fired bool
+}
+
+func newFakeClock() *fakeClock {
+	c := &fakeClock{now: time.Unix(0, 0)}
+	c.cond = sync.NewCond(&c.mu)
+	return c
+}
+
+func (c *fakeClock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.now
+}
+
+func (c *fakeClock) NewTimer(d time.Duration) Timer {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	t := &fakeTimer{clock: c, when: c.now.Add(d), ch: make(chan time.Time, 1)}
+	c.created++
+	if d <= 0 {
+		t.fired = true
+		t.ch <- c.now
+	} else {
+		c.timers = append(c.timers, t)
+	}
+	c.cond.Broadcast()
+	return t
+}
+
+// advance moves the time forward by d and fires the timers that expire.
+func (c *fakeClock) advance(d time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.now = c.now.Add(d)
+	pending := c.timers[:0]
+	for _, t := range c.timers {
+		if !t.when.After(c.now) {
+			t.fired = true
+			t.ch <- c.now
+		} else {
+			pending = append(pending, t)
+		}
+	}
+	c.timers = pending
+}
+
+// waitTimers waits until n timers have been created since the clock was created.
+func (c *fakeClock) waitTimers(n int) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for c.created < n {
+		c.cond.Wait()
+	}
+}
+
+func (t *fakeTimer) C() <-chan time.Time { return t.ch }
+
+func (t *fakeTimer) Stop() bool {
+	c := t.clock
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for i, other := range c.timers {
+		if other == t {
+			c.timers = append(c.timers[:i], c.timers[i+1:]...)
+		}
+	}
+	return !t.fired
+}
+
+// timedTest feeds the elements sent on src to a stream of the fake clock, and
+// collects the results of op in a channel, in its own goroutine.
+func timedTest[O any](op func(s Stream[int]) Stream[O]) (*fakeClock, chan<- int, <-chan O) {
+	clock := newFakeClock()
+	src := make(chan int)
+	out := make(chan O, 100)
+	go func() {
+		defer close(out)
+		op(OfChannel(src).WithClock(clock)).ForEach(func(v O) { out <- v })
+	}()
+	return clock, src, out
+}
+
+func TestBufferTime(t *testing.T) {
+	clock, src, out := timedTest(func(s Stream[int]) Stream[[]int] { return BufferTime(s, time.Second) })
+	// each pull or element starts waiting for a new timer
+	src <- 1
+	src <- 2
+	clock.waitTimers(3)
+	clock.advance(time.Second)
+	if got := <-out; !reflect.DeepEqual(got, []int{1, 2}) {
+		t.Errorf("first batch = %v, want [1 2]", got)
+	}
+	// an empty period emits nothing
+	clock.waitTimers(4)
+	clock.advance(time.Second)
+	src <- 3
+	clock.waitTimers(6)
+	close(src)
+	if got := <-out; !reflect.DeepEqual(got, []int{3}) {
+		t.Errorf("last batch = %v, want [3]", got)
+	}
+	if got, ok := <-out; ok {
+		t.Errorf("unexpected batch %v", got)
+	}
+}
+
+func TestBufferCountOrTime(t *testing.T) {
+	clock, src, out := timedTest(func(s Stream[int]) Stream[[]int] { return BufferCountOrTime(s, 2, time.Second) })
+	src <- 1
+	src <- 2
+	src <- 3
+	if got := <-out; !reflect.DeepEqual(got, []int{1, 2}) {
+		t.Errorf("full batch = %v, want [1 2]", got)
+	}
+	clock.waitTimers(4)
+	clock.advance(time.Second)
+	if got := <-out; !reflect.DeepEqual(got, []int{3}) {
+		t.Errorf("timed batch = %v, want [3]", got)
+	}
+	close(src)
+	if got, ok := <-out; ok {
+		t.Errorf("unexpected batch %v", got)
+	}
+}
+
+func TestThrottle(t *testing.T) {
+	clock := newFakeClock()
+	tick := Generate(func() (int, bool) {
+		now := clock.Now()
+		clock.advance(300 * time.Millisecond)
+		return int(now.UnixMilli()), now.Before(time.UnixMilli(3000))
+	})
+	got := Throttle(tick.WithClock(clock), time.Second).ToSlice()
+	if want := []int{0, 1200, 2400}; !reflect.DeepEqual(got, want) {
+		t.Errorf("Throttle() = %v, want %v", got, want)
+	}
+}
+
+func TestDebounce(t *testing.T) {
+	clock, src, out := timedTest(func(s Stream[int]) Stream[int] { return Debounce(s, time.Second) })
+	src <- 1
+	clock.waitTimers(1)
+	clock.advance(500 * time.Millisecond)
+	src <- 2
+	clock.waitTimers(2)
+	clock.advance(500 * time.Millisecond)
+	src <- 3
+	clock.waitTimers(3)
+	clock.advance(time.Second)
+	if got := <-out; got != 3 {
+		t.Errorf("first element = %v, want 3", got)
+	}
+	src <- 4
+	close(src)
+	if got := <-out; got != 4 {
+		t.Errorf("pending element = %v, want 4", got)
+	}
+	if got, ok := <-out; ok {
+		t.Errorf("unexpected element %v", got)
+	}
+}
+
+func TestSample(t *testing.T) {
+	clock, src, out := timedTest(func(s Stream[int]) Stream[int] { return Sample(s, time.Second) })
+	src <- 1
+	src <- 2
+	clock.waitTimers(3)
+	clock.advance(time.Second)
+	if got := <-out; got != 2 {
+		t.Errorf("first sample = %v, want 2", got)
+	}
+	clock.waitTimers(4)
+	clock.advance(time.Second)
+	src <- 3
+	close(src)
+	if got := <-out; got != 3 {
+		t.Errorf("last sample = %v, want 3", got)
+	}
+	if got, ok := <-out; ok {
+		t.Errorf("unexpected sample %v", got)
+	}
+}
+
+func TestTimeout(t *testing.T) {
+	clock := newFakeClock()
+	src := make(chan int)
+	res := make(chan error)
+	var got []int
+	go func() {
+		var err error
+		got, err = Timeout(OfChannel(src).WithClock(clock), time.Second).ToSliceErr()
+		res <- err
+	}()
+	src <- 1
+	clock.waitTimers(2)
+	clock.advance(999 * time.Millisecond)
+	src <- 2
+	clock.waitTimers(3)
+	clock.advance(time.Second)
+	if err := <-res; !errors.Is(err, ErrTimeout) {
+		t.Errorf("ToSliceErr() error = %v, want %v", err, ErrTimeout)
+	}
+	if want := []int{1, 2}; !reflect.DeepEqual(got, want) {
+		t.Errorf("ToSliceErr() = %v, want %v", got, want)
+	}
+	// unblock the pump goroutine of the closed stream
+	close(src)
+}
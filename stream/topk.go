@@ -0,0 +1,105 @@
+package stream
+
+import "container/heap"
+
+// TopK returns the k greatest elements of the stream according to the provided
+// Comparator, from the greatest to the lowest. Unlike Sorted(Inverse(comparator)).Limit(k)
+// it holds at most k elements in memory per goroutine: every goroutine of a parallel
+// stream keeps its k greatest elements in a heap, and the heaps are merged at the end.
+// The order of equal elements is unspecified.
+// This function is equivalent to invoking s.TopK(k, comparator) as method.
+func TopK[T any](s Stream[T], k int, comparator Comparator[T]) []T {
+	return s.TopK(k, comparator)
+}
+
+func (s Stream[T]) TopK(k int, comparator Comparator[T]) []T {
+	return s.BottomK(k, Inverse(comparator))
+}
+
+// BottomK is like TopK, but returns the k lowest elements of the stream, from the
+// lowest to the greatest, as Sorted(comparator).Limit(k) does.
+// This function is equivalent to invoking s.BottomK(k, comparator) as method.
+func BottomK[T any](s Stream[T], k int, comparator Comparator[T]) []T {
+	return s.BottomK(k, comparator)
+}
+
+func (s Stream[T]) BottomK(k int, comparator Comparator[T]) []T {
+	defer s.pipe.close()
+	return bottomK(s, k, comparator)
+}
+
+// TopKStream returns a stream of the elements returned by TopK, which are selected on
+// the first pull. Like Sorted, the returned stream is ordered and keeps the
+// parallelism of the input stream.
+// This function is equivalent to invoking s.TopKStream(k, comparator) as method.
+func TopKStream[T any](s Stream[T], k int, comparator Comparator[T]) Stream[T] {
+	return s.TopKStream(k, comparator)
+}
+
+func (s Stream[T]) TopKStream(k int, comparator Comparator[T]) Stream[T] {
+	return s.BottomKStream(k, Inverse(comparator))
+}
+
+// BottomKStream returns a stream of the elements returned by BottomK, which are
+// selected on the first pull. Sorted(comparator).Limit(k) is rewritten into it.
+// This function is equivalent to invoking s.BottomKStream(k, comparator) as method.
+func BottomKStream[T any](s Stream[T], k int, comparator Comparator[T]) Stream[T] {
+	return s.BottomKStream(k, comparator)
+}
+
+func (s Stream[T]) BottomKStream(k int, comparator Comparator[T]) Stream[T] {
+	return lazySlice(s.config, func() []T { return bottomK(s, k, comparator) })
+}
+
+// bottomK is BottomK without closing the pipeline. The input stream is not pulled
+// if k is not positive.
+func bottomK[T any](s Stream[T], k int, comparator Comparator[T]) []T {
+	if k <= 0 {
+		return []T{}
+	}
+	h := reduce(s,
+		func() *boundedHeap[T] { return &boundedHeap[T]{k: k, cmp: comparator} },
+		func(h *boundedHeap[T], v T) *boundedHeap[T] {
+			h.offer(v)
+			return h
+		},
+		func(h1, h2 *boundedHeap[T]) *boundedHeap[T] {
+			for _, v := range h2.elems {
+				h1.offer(v)
+			}
+			return h1
+		})
+	SortSlice(h.elems, comparator)
+	return h.elems
+}
+
+// boundedHeap keeps the k lowest elements offered, in a heap whose root is the
+// greatest of them, so an element is only kept if it is lower than the root.
+type boundedHeap[T any] struct {
+	elems []T
+	k     int
+	cmp   Comparator[T]
+}
+
+func (h *boundedHeap[T]) offer(v T) {
+	if len(h.elems) < h.k {
+		heap.Push(h, v)
+	} else if h.cmp(v, h.elems[0]) < 0 {
+		h.elems[0] = v
+		heap.Fix(h, 0)
+	}
+}
+
+func (h *boundedHeap[T]) Len() int { return len(h.elems) }
+
+func (h *boundedHeap[T]) Less(i, j int) bool { return h.cmp(h.elems[i], h.elems[j]) > 0 }
+
+func (h *boundedHeap[T]) Swap(i, j int) { h.elems[i], h.elems[j] = h.elems[j], h.elems[i] }
+
+func (h *boundedHeap[T]) Push(x any) { h.elems = append(h.elems, x.(T)) }
+
+func (h *boundedHeap[T]) Pop() any {
+	last := h.elems[len(h.elems)-1]
+	h.elems = h.elems[:len(h.elems)-1]
+	return last
+}
@@ -0,0 +1,67 @@
+package stream
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestTopK(t *testing.T) {
+	input := randomSlice(10000)
+	sorted := append([]int{}, input...)
+	SortSlice(sorted, Natural[int])
+	reversed := append([]int{}, sorted...)
+	SortSlice(reversed, Inverse(Natural[int]))
+
+	for _, p := range []int{1, 4} {
+		tests := []struct {
+			name string
+			got  []int
+			want []int
+		}{
+			{"TopK", TopK(OfSlice(input).Parallel(p), 10, Natural[int]), reversed[:10]},
+			{"BottomK", OfSlice(input).Parallel(p).BottomK(10, Natural[int]), sorted[:10]},
+			{"TopK more than the stream", Of(2, 3, 1).Parallel(p).TopK(5, Natural[int]), []int{3, 2, 1}},
+			{"BottomK zero", OfSlice(input).Parallel(p).BottomK(0, Natural[int]), []int{}},
+			{"TopKStream", TopKStream(OfSlice(input).Parallel(p), 10, Natural[int]).ToSlice(), reversed[:10]},
+			{"BottomKStream", BottomKStream(OfSlice(input).Parallel(p), 10, Natural[int]).ToSlice(), sorted[:10]},
+			{"Sorted Limit", OfSlice(input).Parallel(p).Sorted(Natural[int]).Limit(10).ToSlice(), sorted[:10]},
+			{"Sorted Limit zero", OfSlice(input).Parallel(p).Sorted(Natural[int]).Limit(0).ToSlice(), []int{}},
+		}
+		for _, tt := range tests {
+			if !reflect.DeepEqual(tt.got, tt.want) {
+				t.Errorf("parallel %d: %s = %v, want %v", p, tt.name, tt.got, tt.want)
+			}
+		}
+	}
+
+	t.Run("Sorted Limit rewrite", func(t *testing.T) {
+		s := Range(0, 100).Parallel(4).Sorted(Natural[int])
+		if s.limit == nil {
+			t.Fatal("Sorted() has no limit rewrite")
+		}
+		limited := s.Limit(3)
+		if !limited.ordered || limited.parallel != 4 {
+			t.Errorf("Sorted().Limit() ordered = %v, parallel = %d", limited.ordered, limited.parallel)
+		}
+		if got := limited.ToSlice(); !reflect.DeepEqual(got, []int{0, 1, 2}) {
+			t.Errorf("Sorted().Limit() = %v", got)
+		}
+		if Range(0, 3).SortedStable(Natural[int]).limit != nil {
+			t.Error("SortedStable() has a limit rewrite")
+		}
+	})
+}
+
+func BenchmarkTopK(b *testing.B) {
+	input := randomSlice(1_000_000)
+	b.Run("Sorted Limit", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			OfSlice(input).Sorted(Natural[int]).Take(100).ToSlice()
+		}
+	})
+	b.Run("full sort", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			OfSlice(input).Sorted(Natural[int]).ToSlice()
+		}
+	})
+}
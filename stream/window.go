@@ -0,0 +1,128 @@
+package stream
+
+import "sync"
+
+// Chunk returns a stream of the elements of the input stream grouped in consecutive,
+// non-overlapping slices of size elements (at least 1). The last chunk holds the
+// remaining elements and may be shorter; no empty chunk is emitted. Every chunk is a
+// new slice that the consumer owns.
+//
+// The chunks are built lazily, one at a time, so Chunk works on infinite streams.
+// For parallel streams each chunk is built under a lock from consecutive pulls, so
+// it holds elements in the order they were pulled, which is the encounter order only
+// for sequential and ordered streams; the chunks themselves are then consumed by
+// the goroutines of the parallel stream like any other element.
+func Chunk[T any](s Stream[T], size int) Stream[[]T] {
+	size = max(size, 1)
+	var lock sync.Mutex
+	done := false
+	return Stream[[]T]{
+		config: s.config,
+		nextFn: func() ([]T, bool) {
+			lock.Lock()
+			defer lock.Unlock()
+			if done {
+				return nil, false
+			}
+			chunk := make([]T, 0, size)
+			for len(chunk) < size {
+				v, hasNext := s.nextFn()
+				if !hasNext {
+					done = true
+					break
+				}
+				chunk = append(chunk, v)
+			}
+			return chunk, len(chunk) > 0
+		},
+	}
+}
+
+// Window returns a stream of the sliding windows of size elements (at least 1) over
+// the input stream, starting every step elements (at least 1): with size 3 and step
+// 1 the windows of 1, 2, 3, 4 are [1 2 3] and [2 3 4]. If step is greater than size
+// the elements between two windows are skipped. Only full windows are emitted, so
+// the trailing elements that don't fill a window are dropped, and a stream shorter
+// than size yields no window; use Chunk to keep a trailing partial group.
+//
+// Overlapping windows share their backing array, which is never modified once a
+// window has been emitted, so the consumer must not modify them either; copy a
+// window to keep a mutable version. Windows are built lazily and follow the same
+// rules as Chunk for parallel streams.
+func Window[T any](s Stream[T], size, step int) Stream[[]T] {
+	size, step = max(size, 1), max(step, 1)
+	var lock sync.Mutex
+	// buf[start:] holds the elements of the next window pulled so far, and the
+	// elements before start belong to windows already emitted
+	var buf []T
+	start, skip := 0, 0
+	done := false
+	return Stream[[]T]{
+		config: s.config,
+		nextFn: func() ([]T, bool) {
+			lock.Lock()
+			defer lock.Unlock()
+			for !done && len(buf)-start < size {
+				v, hasNext := s.nextFn()
+				if !hasNext {
+					done = true
+					break
+				}
+				if skip > 0 {
+					skip--
+					continue
+				}
+				if len(buf) == cap(buf) {
+					// move the pending elements to a new array, appending never
+					// overwrites the emitted windows
+					next := make([]T, len(buf)-start, 4*size)
+					copy(next, buf[start:])
+					buf, start = next, 0
+				}
+				buf = append(buf, v)
+			}
+			if len(buf)-start < size {
+				buf = nil
+				return nil, false
+			}
+			window := buf[start : start+size : start+size]
+			start += step
+			if start > len(buf) {
+				// step > size, skip the elements between this window and the next
+				skip = start - len(buf)
+				buf, start = nil, 0
+			}
+			return window, true
+		},
+	}
+}
+
+// Pairwise returns a stream of the pairs of consecutive elements of the input stream:
+// the pairs of 1, 2, 3 are [1 2] and [2 3]. A stream of less than two elements yields
+// no pair. Pairs follow the same rules as Chunk for parallel streams.
+func Pairwise[T any](s Stream[T]) Stream[[2]T] {
+	var lock sync.Mutex
+	var prev T
+	started := false
+	return Stream[[2]T]{
+		config: s.config,
+		nextFn: func() ([2]T, bool) {
+			lock.Lock()
+			defer lock.Unlock()
+			if !started {
+				v, hasNext := s.nextFn()
+				if !hasNext {
+					return [2]T{}, false
+				}
+				prev, started = v, true
+			}
+			v, hasNext := s.nextFn()
+			if !hasNext {
+				return [2]T{}, false
+			}
+			pair := [2]T{prev, v}
+			prev = v
+			return pair, true
+		},
+	}
+}
@@ -0,0 +1,92 @@
+package stream
+
+import (
+	"reflect"
+	"sort"
+	"testing"
+)
+
+func TestChunk(t *testing.T) {
+	tests := []struct {
+		name string
+		s    Stream[int]
+		size int
+		want [][]int
+	}{
+		{"exact", Range(0, 6), 3, [][]int{{0, 1, 2}, {3, 4, 5}}},
+		{"trailing partial", Range(0, 5), 2, [][]int{{0, 1}, {2, 3}, {4}}},
+		{"empty", Of[int](), 2, [][]int{}},
+		{"size below 1", Of(1, 2), 0, [][]int{{1}, {2}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Chunk(tt.s, tt.size).ToSlice(); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Chunk() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+
+	infinite := Generate(func() (int, bool) { return 7, true })
+	if got, want := Chunk(infinite, 2).Limit(2).ToSlice(), [][]int{{7, 7}, {7, 7}}; !reflect.DeepEqual(got, want) {
+		t.Errorf("Chunk() of an infinite stream = %v, want %v", got, want)
+	}
+}
+
+func TestWindow(t *testing.T) {
+	tests := []struct {
+		name       string
+		s          Stream[int]
+		size, step int
+		want       [][]int
+	}{
+		{"sliding", Range(1, 6), 3, 1, [][]int{{1, 2, 3}, {2, 3, 4}, {3, 4, 5}}},
+		{"step 2", Range(1, 8), 3, 2, [][]int{{1, 2, 3}, {3, 4, 5}, {5, 6, 7}}},
+		{"tumbling", Range(1, 8), 3, 3, [][]int{{1, 2, 3}, {4, 5, 6}}},
+		{"step above size", Range(1, 12), 2, 4, [][]int{{1, 2}, {5, 6}, {9, 10}}},
+		{"shorter than size", Range(1, 3), 3, 1, [][]int{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Window(tt.s, tt.size, tt.step).ToSlice(); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Window() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+
+	t.Run("long stream", func(t *testing.T) {
+		windows := Window(Range(0, 1000), 5, 1).ToSlice()
+		if len(windows) != 996 {
+			t.Fatalf("got %d windows, want 996", len(windows))
+		}
+		for i, w := range windows {
+			if want := Range(i, i+5).ToSlice(); !reflect.DeepEqual(w, want) {
+				t.Fatalf("window %d = %v, want %v", i, w, want)
+			}
+		}
+	})
+}
+
+func TestPairwise(t *testing.T) {
+	if got, want := Pairwise(Of(1, 2, 3)).ToSlice(), [][2]int{{1, 2}, {2, 3}}; !reflect.DeepEqual(got, want) {
+		t.Errorf("Pairwise() = %v, want %v", got, want)
+	}
+	if got := Pairwise(Of(1)).ToSlice(); len(got) != 0 {
+		t.Errorf("Pairwise() = %v, want no pair", got)
+	}
+	// an ordered parallel upstream keeps the encounter order
+	got := Pairwise(Map(Range(0, 100).ParallelOrdered(4), func(n int) int { return n * 2 })).ToSlice()
+	for i, p := range got {
+		if p != [2]int{2 * i, 2*i + 2} {
+			t.Fatalf("pair %d = %v", i, p)
+		}
+	}
+	// a parallel upstream yields every element once in some chunk
+	var all []int
+	for _, c := range Chunk(Range(0, 100).Parallel(4), 7).Parallel(4).ToSlice() {
+		all = append(all, c...)
+	}
+	sort.Ints(all)
+	if want := Range(0, 100).ToSlice(); !reflect.DeepEqual(all, want) {
+		t.Errorf("Chunk() of a parallel stream = %v, want %v", all, want)
+	}
+}
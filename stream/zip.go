@@ -0,0 +1,94 @@
+package stream
+
+import (
+	"math"
+	"sync"
+)
+
+// Zip returns a stream of the pairs of the elements at the same position of a and b.
+// It ends when the shorter stream ends; if a ends first b is not pulled any further,
+// and if b ends first the last element pulled from a is dropped.
+//
+// Each pair is formed by pulling a and then b under a lock, so the pulls of both
+// streams stay aligned even when the zipped stream is consumed by several goroutines.
+// The positions are the order in which a and b yield their elements, which is the
+// encounter order for sequential and ordered streams; a parallel unordered input
+// yields its elements in no particular order. The resulting stream is parallel if
+// either input is, and ordered if both are, as for Concat, and closing it closes
+// both inputs.
+func Zip[A, B any](a Stream[A], b Stream[B]) Stream[Pair[A, B]] {
+	var lock sync.Mutex
+	done := false
+	return Stream[Pair[A, B]]{
+		config: zipConfig(a.config, b.config),
+		nextFn: func() (Pair[A, B], bool) {
+			lock.Lock()
+			defer lock.Unlock()
+			if done {
+				return Pair[A, B]{}, false
+			}
+			va, hasNext := a.nextFn()
+			if !hasNext {
+				done = true
+				return Pair[A, B]{}, false
+			}
+			vb, hasNext := b.nextFn()
+			if !hasNext {
+				done = true
+				return Pair[A, B]{}, false
+			}
+			return PairOf(va, vb), true
+		},
+	}
+}
+
+// ZipWith returns a stream of the results of applying fn to the elements at the same
+// position of a and b, see Zip. For parallel streams fn runs in the goroutines of the
+// stream, after the elements are paired.
+func ZipWith[A, B, O any](a Stream[A], b Stream[B], fn func(A, B) O) Stream[O] {
+	return Map(Zip(a, b), func(p Pair[A, B]) O { return fn(p.First, p.Second) })
+}
+
+// ZipLongest is like Zip, but it ends when the longer stream ends: the elements
+// missing from the shorter stream are absent Optional values.
+func ZipLongest[A, B any](a Stream[A], b Stream[B]) Stream[Pair[Optional[A], Optional[B]]] {
+	var lock sync.Mutex
+	doneA, doneB := false, false
+	return Stream[Pair[Optional[A], Optional[B]]]{
+		config: zipConfig(a.config, b.config),
+		nextFn: func() (Pair[Optional[A], Optional[B]], bool) {
+			lock.Lock()
+			defer lock.Unlock()
+			var p Pair[Optional[A], Optional[B]]
+			if !doneA {
+				p.First.Value, p.First.Ok = a.nextFn()
+				doneA = !p.First.Ok
+			}
+			if !doneB {
+				p.Second.Value, p.Second.Ok = b.nextFn()
+				doneB = !p.Second.Ok
+			}
+			return p, p.First.Ok || p.Second.Ok
+		},
+	}
+}
+
+// Unzip returns the streams of the first and second elements of the pairs of the
+// input stream, which is pulled only once. The pairs pulled by one of the streams
+// are buffered until the other one pulls them, so both streams can be consumed one
+// after the other, at the cost of buffering all the elements in the meantime.
+func Unzip[A, B any](s Stream[Pair[A, B]]) (Stream[A], Stream[B]) {
+	branches := Tee(s, 2, math.MaxInt, Block)
+	return Map(branches[0], func(p Pair[A, B]) A { return p.First }),
+		Map(branches[1], func(p Pair[A, B]) B { return p.Second })
+}
+
+// zipConfig returns the config of a stream combining the elements of two streams.
+func zipConfig(a, b config) config {
+	return config{
+		parallel: max(a.parallel, b.parallel),
+		ordered:  a.ordered && b.ordered,
+		pipe:     joinPipelines(a.pipe, b.pipe),
+		clock:    a.clock,
+	}
+}
@@ -0,0 +1,81 @@
+package stream
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestZip(t *testing.T) {
+	tests := []struct {
+		name string
+		a    Stream[int]
+		b    Stream[string]
+		want []Pair[int, string]
+	}{
+		{"same length", Of(1, 2), Of("a", "b"), []Pair[int, string]{{1, "a"}, {2, "b"}}},
+		{"shorter first", Of(1), Of("a", "b"), []Pair[int, string]{{1, "a"}}},
+		{"shorter second", Of(1, 2, 3), Of("a"), []Pair[int, string]{{1, "a"}}},
+		{"empty", Of[int](), Of("a"), []Pair[int, string]{}},
+		{
+			"infinite",
+			Generate(func() (int, bool) { return 0, true }),
+			Of("a", "b"),
+			[]Pair[int, string]{{0, "a"}, {0, "b"}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Zip(tt.a, tt.b).ToSlice(); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Zip() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+
+	t.Run("aligned when parallel", func(t *testing.T) {
+		square := func(n int) int { return n * n }
+		a := Map(Range(0, 1000).ParallelOrdered(4), square)
+		got := Zip(a, Range(0, 1000)).Parallel(8).ToSlice()
+		if len(got) != 1000 {
+			t.Fatalf("got %d pairs, want 1000", len(got))
+		}
+		for _, p := range got {
+			if p.First != square(p.Second) {
+				t.Fatalf("misaligned pair %v", p)
+			}
+		}
+	})
+}
+
+func TestZipWith(t *testing.T) {
+	got := ZipWith(Of(1, 2, 3), Of(10, 20, 30), func(a, b int) int { return a + b }).ToSlice()
+	if want := []int{11, 22, 33}; !reflect.DeepEqual(got, want) {
+		t.Errorf("ZipWith() = %v, want %v", got, want)
+	}
+}
+
+func TestZipLongest(t *testing.T) {
+	got := ZipLongest(Of(1, 2, 3), Of("a")).ToSlice()
+	want := []Pair[Optional[int], Optional[string]]{
+		{Some(1), Some("a")},
+		{Some(2), Optional[string]{}},
+		{Some(3), Optional[string]{}},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("ZipLongest() = %v, want %v", got, want)
+	}
+	if got := got[1].Second.OrElse("-"); got != "-" {
+		t.Errorf("OrElse() = %v, want -", got)
+	}
+}
+
+func TestUnzip(t *testing.T) {
+	nums, names := Unzip(Zip(Range(0, 500), Map(Range(0, 500), func(n int) string { return string(rune('a' + n%26)) })))
+	gotNums := nums.ToSlice()
+	gotNames := names.ToSlice()
+	if want := Range(0, 500).ToSlice(); !reflect.DeepEqual(gotNums, want) {
+		t.Errorf("first stream = %v, want %v", gotNums, want)
+	}
+	if len(gotNames) != 500 || gotNames[27] != "b" {
+		t.Errorf("second stream = %v", gotNames)
+	}
+}